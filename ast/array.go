@@ -15,6 +15,10 @@ func (a *ArrayExpression) TokenLiteral() string {
 	return a.Token.Literal
 }
 
+func (a *ArrayExpression) Pos() token.Position {
+	return a.Token.Pos
+}
+
 func (a *ArrayExpression) String() string {
 	var out bytes.Buffer
 	var str []string
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"hek/token"
 )
 
 type AssigExpression struct {
@@ -13,6 +14,10 @@ func (a *AssigExpression) TokenLiteral() string {
 	return a.Name.TokenLiteral()
 }
 
+func (a *AssigExpression) Pos() token.Position {
+	return a.Name.Pos()
+}
+
 func (a *AssigExpression) expressionNode() {}
 
 func (a *AssigExpression) String() string {
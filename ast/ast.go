@@ -1,8 +1,11 @@
 package ast
 
+import "hek/token"
+
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 type Statement interface {
 	Node
@@ -14,6 +14,10 @@ func (b *BlockStatement) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *BlockStatement) Pos() token.Position {
+	return b.Token.Pos
+}
+
 func (b *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -11,6 +11,10 @@ func (b *BoolExpression) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *BoolExpression) Pos() token.Position {
+	return b.Token.Pos
+}
+
 func (b *BoolExpression) String() string {
 	return b.Token.Literal
 }
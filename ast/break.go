@@ -0,0 +1,51 @@
+package ast
+
+import "hek/token"
+
+type BreakStatement struct {
+	Token token.Token
+	Label *Identifier //break outer, 没有标签时为 nil
+}
+
+func (b *BreakStatement) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (b *BreakStatement) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *BreakStatement) String() string {
+	if b.Label != nil {
+		return b.TokenLiteral() + " " + b.Label.String() + ";"
+	}
+	return b.TokenLiteral() + ";"
+}
+
+func (b *BreakStatement) statementNode() {
+
+}
+
+type ContinueStatement struct {
+	Token token.Token
+	Label *Identifier //continue outer, 没有标签时为 nil
+}
+
+func (c *ContinueStatement) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (c *ContinueStatement) Pos() token.Position {
+	return c.Token.Pos
+}
+
+func (c *ContinueStatement) String() string {
+	if c.Label != nil {
+		return c.TokenLiteral() + " " + c.Label.String() + ";"
+	}
+	return c.TokenLiteral() + ";"
+}
+
+func (c *ContinueStatement) statementNode() {
+
+}
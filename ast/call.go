@@ -16,6 +16,10 @@ func (c *CallExpression) TokenLiteral() string {
 	return c.Token.Literal
 }
 
+func (c *CallExpression) Pos() token.Position {
+	return c.Token.Pos
+}
+
 func (c *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -0,0 +1,24 @@
+package ast
+
+import "hek/token"
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (f *FloatLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f *FloatLiteral) String() string {
+	return f.Token.Literal
+}
+
+func (f *FloatLiteral) expressionNode() {
+
+}
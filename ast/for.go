@@ -11,14 +11,22 @@ type ForExpression struct {
 	Mid   Expression
 	Right Expression
 	Block *BlockStatement
+	Label *Identifier //outer: for(...), 没有标签时为 nil
 }
 
 func (f *ForExpression) TokenLiteral() string {
 	return f.Token.Literal
 }
 
+func (f *ForExpression) Pos() token.Position {
+	return f.Token.Pos
+}
+
 func (f *ForExpression) String() string {
 	var out bytes.Buffer
+	if f.Label != nil {
+		out.WriteString(f.Label.String() + ": ")
+	}
 	out.WriteString("for(" + f.Left.String() + ";" + f.Mid.String() + ";" + f.Right.String() + ") {\n")
 	out.WriteString(f.Block.String() + "\n}")
 	return out.String()
@@ -7,23 +7,37 @@ import (
 )
 
 type FunExpression struct {
-	Token  token.Token
-	Params []*Identifier
-	Block  *BlockStatement
-	Name   *Identifier
+	Token    token.Token
+	Params   []*Identifier
+	Defaults []Expression //与 Params 一一对应, 没有默认值时为 nil
+	Rest     *Identifier  //可变参数 ...rest, 不在 Params 中
+	Block    *BlockStatement
+	Name     *Identifier
+	Doc      string //声明之前的文档注释
 }
 
 func (f *FunExpression) TokenLiteral() string {
 	return f.Token.Literal
 }
 
+func (f *FunExpression) Pos() token.Position {
+	return f.Token.Pos
+}
+
 func (f *FunExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("fun " + f.TokenLiteral())
 	var params []string
-	for _, param := range f.Params {
+	for i, param := range f.Params {
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			params = append(params, param.String()+"="+f.Defaults[i].String())
+			continue
+		}
 		params = append(params, param.String())
 	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
+	}
 	out.WriteString("(" + strings.Join(params, ",") + ")")
 	out.WriteString(f.Block.String())
 	return out.String()
@@ -5,20 +5,29 @@ import (
 	"hek/token"
 )
 
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
 type HashExpression struct {
 	Token token.Token
-	Value map[Expression]Expression
+	Pairs []*HashPair //按源码中的顺序排列
 }
 
 func (h *HashExpression) TokenLiteral() string {
 	return h.Token.Literal
 }
 
+func (h *HashExpression) Pos() token.Position {
+	return h.Token.Pos
+}
+
 func (h *HashExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("{")
-	for index, val := range h.Value {
-		out.WriteString(index.String() + ":" + val.String() + ",")
+	for _, pair := range h.Pairs {
+		out.WriteString(pair.Key.String() + ":" + pair.Value.String() + ",")
 	}
 	out.WriteString("}")
 	return out.String()
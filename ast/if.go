@@ -16,6 +16,10 @@ func (I *IFExpression) TokenLiteral() string {
 	return I.Token.Literal
 }
 
+func (I *IFExpression) Pos() token.Position {
+	return I.Token.Pos
+}
+
 func (I *IFExpression) String() string {
 	var out bytes.Buffer
 
@@ -15,6 +15,10 @@ func (i *IndexExpression) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *IndexExpression) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i *IndexExpression) String() string {
 	var out bytes.Buffer
 
@@ -15,6 +15,10 @@ func (i *InfixExpression) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *InfixExpression) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -11,6 +11,10 @@ func (i *IntegerLiteral) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *IntegerLiteral) Pos() token.Position {
+	return i.Token.Pos
+}
+
 func (i *IntegerLiteral) String() string {
 	return i.Token.Literal
 }
@@ -0,0 +1,38 @@
+package ast
+
+import (
+	"bytes"
+	"hek/token"
+)
+
+// InterpolatedString "hello ${name}", Parts 依次为字符串片段与插值表达式
+type InterpolatedString struct {
+	Token token.Token
+	Parts []Expression
+}
+
+func (i *InterpolatedString) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i *InterpolatedString) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString("\"")
+	for _, part := range i.Parts {
+		if s, ok := part.(*StringExpression); ok {
+			out.WriteString(s.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+	return out.String()
+}
+
+func (i *InterpolatedString) expressionNode() {
+
+}
@@ -9,12 +9,17 @@ type LetStatement struct {
 	Token token.Token
 	Name  *Identifier
 	Value Expression
+	Doc   string //声明之前的文档注释
 }
 
 func (l *LetStatement) TokenLiteral() string {
 	return l.Token.Literal
 }
 
+func (l *LetStatement) Pos() token.Position {
+	return l.Token.Pos
+}
+
 func (l *LetStatement) statementNode() {}
 
 func (l *LetStatement) String() string {
@@ -0,0 +1,26 @@
+package ast
+
+import "hek/token"
+
+// NamedArgument 调用时按参数名传入的参数 f(a, c = 9)
+type NamedArgument struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (n *NamedArgument) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NamedArgument) Pos() token.Position {
+	return n.Token.Pos
+}
+
+func (n *NamedArgument) String() string {
+	return n.Name.String() + "=" + n.Value.String()
+}
+
+func (n *NamedArgument) expressionNode() {
+
+}
@@ -14,6 +14,10 @@ func (p *PrefixExpression) TokenLiteral() string {
 	return p.Token.Literal
 }
 
+func (p *PrefixExpression) Pos() token.Position {
+	return p.Token.Pos
+}
+
 func (p *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -14,6 +14,10 @@ func (r *ReturnStatement) TokenLiteral() string {
 	return r.Token.Literal
 }
 
+func (r *ReturnStatement) Pos() token.Position {
+	return r.Token.Pos
+}
+
 func (r ReturnStatement) statementNode() {
 
 }
@@ -0,0 +1,42 @@
+package ast
+
+import (
+	"bytes"
+	"hek/token"
+)
+
+// SliceExpression a[start:end], 省略的下标为 nil
+type SliceExpression struct {
+	Token token.Token
+	Left  Expression
+	Start Expression
+	End   Expression
+}
+
+func (s *SliceExpression) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *SliceExpression) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(s.Left.String())
+	out.WriteString("[")
+	if s.Start != nil {
+		out.WriteString(s.Start.String())
+	}
+	out.WriteString(":")
+	if s.End != nil {
+		out.WriteString(s.End.String())
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+func (s *SliceExpression) expressionNode() {
+
+}
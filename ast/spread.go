@@ -0,0 +1,25 @@
+package ast
+
+import "hek/token"
+
+// SpreadExpression 调用时展开数组 f(...arr)
+type SpreadExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+func (s *SpreadExpression) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *SpreadExpression) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s *SpreadExpression) String() string {
+	return "..." + s.Value.String()
+}
+
+func (s *SpreadExpression) expressionNode() {
+
+}
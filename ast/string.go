@@ -11,6 +11,10 @@ func (s *StringExpression) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (s *StringExpression) Pos() token.Position {
+	return s.Token.Pos
+}
+
 func (s *StringExpression) String() string {
 	return s.Value
 }
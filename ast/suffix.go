@@ -14,6 +14,10 @@ func (s *SuffixExpression) TokenLiteral() string {
 	return s.Token.Literal
 }
 
+func (s *SuffixExpression) Pos() token.Position {
+	return s.Token.Pos
+}
+
 func (s *SuffixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(s.Left.String())
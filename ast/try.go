@@ -0,0 +1,64 @@
+package ast
+
+import (
+	"bytes"
+	"hek/token"
+)
+
+// TryStatement try {} catch (e) {} finally {}, catch 与 finally 至少有一个, 没有时为 nil
+type TryStatement struct {
+	Token   token.Token
+	Block   *BlockStatement
+	Param   *Identifier //catch (e) 中的 e, 可以省略
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (t *TryStatement) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *TryStatement) Pos() token.Position {
+	return t.Token.Pos
+}
+
+func (t *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try " + t.Block.String())
+	if t.Catch != nil {
+		out.WriteString(" catch ")
+		if t.Param != nil {
+			out.WriteString("(" + t.Param.String() + ") ")
+		}
+		out.WriteString(t.Catch.String())
+	}
+	if t.Finally != nil {
+		out.WriteString(" finally " + t.Finally.String())
+	}
+	return out.String()
+}
+
+func (t *TryStatement) statementNode() {
+
+}
+
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (t *ThrowStatement) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *ThrowStatement) Pos() token.Position {
+	return t.Token.Pos
+}
+
+func (t *ThrowStatement) String() string {
+	return t.TokenLiteral() + " " + t.Value.String() + ";"
+}
+
+func (t *ThrowStatement) statementNode() {
+
+}
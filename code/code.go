@@ -47,6 +47,27 @@ const (
 	OpDelLocal
 	OpSetIndexGlobal
 	OpSetIndexLocal
+	OpHash
+	OpGTE
+	OpLTE
+	OpMod
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShl
+	OpShr
+	OpBitNot
+	OpStringBuild
+	OpSlice
+	OpSetFree
+	OpSetIndexFree
+	OpDefaultArg
+	OpCallSpread
+	OpTry
+	OpPopHandler
+	OpThrow
+	OpSetIndex
+	OpCallNamed
 )
 
 type Definitions struct {
@@ -68,7 +89,7 @@ var definitions = map[Opcode]*Definitions{
 	OpLT:             {"opLT", []int{}},
 	OpGT:             {"opGT", []int{}},
 	OpBang:           {"opBang", []int{}},
-	OpMinus:          {"opBang", []int{}},
+	OpMinus:          {"opMinus", []int{}},
 	OpJumpNotTrueThy: {"opJumpNotTrueThy", []int{2}},
 	OpJump:           {"opJump", []int{2}},
 	OpNull:           {"opNull", []int{}},
@@ -90,6 +111,27 @@ var definitions = map[Opcode]*Definitions{
 	OpDelLocal:       {"opDelLocal", []int{2}},
 	OpSetIndexGlobal: {"opSetIndexGlobal", []int{2}},
 	OpSetIndexLocal:  {"opSetIndexLocal", []int{2}},
+	OpHash:           {"opHash", []int{2}},
+	OpGTE:            {"opGTE", []int{}},
+	OpLTE:            {"opLTE", []int{}},
+	OpMod:            {"opMod", []int{}},
+	OpBitAnd:         {"opBitAnd", []int{}},
+	OpBitOr:          {"opBitOr", []int{}},
+	OpBitXor:         {"opBitXor", []int{}},
+	OpShl:            {"opShl", []int{}},
+	OpShr:            {"opShr", []int{}},
+	OpBitNot:         {"opBitNot", []int{}},
+	OpStringBuild:    {"opStringBuild", []int{2}},
+	OpSlice:          {"opSlice", []int{}},
+	OpSetFree:        {"opSetFree", []int{2}},
+	OpSetIndexFree:   {"opSetIndexFree", []int{2}},
+	OpDefaultArg:     {"opDefaultArg", []int{2, 2}},
+	OpCallSpread:     {"opCallSpread", []int{2}},
+	OpTry:            {"opTry", []int{2}},
+	OpPopHandler:     {"opPopHandler", []int{}},
+	OpThrow:          {"opThrow", []int{}},
+	OpSetIndex:       {"opSetIndex", []int{}},
+	OpCallNamed:      {"opCallNamed", []int{2, 2}},
 }
 
 func Lookup(op byte) (*Definitions, error) {
@@ -0,0 +1,46 @@
+package code
+
+import "hek/token"
+
+// LineEntry 从 Offset 开始的指令对应的源码位置
+type LineEntry struct {
+	Offset int
+	Pos    token.Position
+}
+
+// LineTable 指令偏移到源码位置的映射, 按 Offset 递增排列
+type LineTable []LineEntry
+
+func (l LineTable) Add(offset int, pos token.Position) LineTable {
+	if !pos.IsValid() {
+		return l
+	}
+	if len(l) > 0 && l[len(l)-1].Pos == pos {
+		return l
+	}
+	if len(l) > 0 && l[len(l)-1].Offset == offset {
+		l[len(l)-1].Pos = pos
+		return l
+	}
+	return append(l, LineEntry{Offset: offset, Pos: pos})
+}
+
+// Truncate 删除 offset 及之后的记录
+func (l LineTable) Truncate(offset int) LineTable {
+	for len(l) > 0 && l[len(l)-1].Offset >= offset {
+		l = l[:len(l)-1]
+	}
+	return l
+}
+
+// Lookup 返回 offset 处指令的源码位置
+func (l LineTable) Lookup(offset int) token.Position {
+	var pos token.Position
+	for _, entry := range l {
+		if entry.Offset > offset {
+			break
+		}
+		pos = entry.Pos
+	}
+	return pos
+}
@@ -21,8 +21,14 @@ func NewCompileCache(symbolTable *SymbolTable, constants []object.Object) *Compi
 	return c
 }
 func (c *Compiler) Compile(node ast.Node) error {
+	if pos := node.Pos(); pos.IsValid() {
+		prev := c.position
+		c.position = pos
+		defer func() { c.position = prev }()
+	}
 	switch n := node.(type) {
 	case *ast.Program:
+		c.declare(n.Statements)
 		for _, statement := range n.Statements {
 			e := c.callBack(statement)
 			if e != nil {
@@ -40,6 +46,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		return c.infixExpression(n)
 	case *ast.IntegerLiteral:
 		c.IntegerLiteral(n)
+	case *ast.FloatLiteral:
+		c.emit(code.OpConstant, c.addConstant(&object.Float{Value: n.Value}))
 	case *ast.BoolExpression:
 		if n.Value {
 			c.emit(code.OpTrue)
@@ -51,7 +59,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		return c.prefixExpression(n.Token.Type)
+		return c.prefixExpression(n.Token)
 	case *ast.IFExpression:
 		return c.ifExpression(n)
 	case *ast.BlockStatement:
@@ -62,11 +70,21 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 	case *ast.LetStatement:
-		symbol := c.symbolTable.SetSymbol(n.Name.Value)
-		err := c.callBack(n.Value)
+		//函数可以在定义中递归调用自己, 其他值与解释器一样先计算初始值再声明变量
+		//所以 let x = x + 1 中右边的 x 是外层的变量
+		var symbol *Symbol
+		var err error
+		if f, ok := n.Value.(*ast.FunExpression); ok && f.Name == nil {
+			symbol = c.symbolTable.Hoist(n.Name.Value)
+			err = c.fun(f, n.Name.Value)
+		} else {
+			err = c.callBack(n.Value)
+			symbol = c.symbolTable.DefineSymbol(n.Name.Value)
+		}
 		if err != nil {
 			return err
 		}
+		symbol.pending = false
 
 		if symbol.types == Global {
 			c.emit(code.OpSetGlobal, symbol.index)
@@ -75,13 +93,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.GetSymbol(n.Value)
-		if !ok {
+		if !ok || c.symbolTable.Uninitialised(n.Value) {
 			pos, ok_ := object.GetNameIndex(n.Value)
 			if ok_ {
 				c.emit(code.OpInternalFun, pos)
 				return nil
 			}
-			return errors.New(fmt.Sprintf("使用了未定义的变量 %s", n.Value))
+			return errors.New(fmt.Sprintf("%s: 使用了未定义的变量 %s", n.Pos(), n.Value))
 		}
 		c.symbolEmitGet(symbol)
 	case *ast.StringExpression:
@@ -96,6 +114,26 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		c.emit(code.OpArray, len(n.Value))
+	case *ast.InterpolatedString:
+		for _, part := range n.Parts {
+			err := c.callBack(part)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpStringBuild, len(n.Parts))
+	case *ast.HashExpression:
+		for _, pair := range n.Pairs {
+			err := c.callBack(pair.Key)
+			if err != nil {
+				return err
+			}
+			err = c.callBack(pair.Value)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(n.Pairs)*2)
 	case *ast.IndexExpression:
 		err := c.callBack(n.Left)
 		if err != nil {
@@ -106,18 +144,61 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 		c.emit(code.OpIndex)
+	case *ast.SliceExpression:
+		err := c.callBack(n.Left)
+		if err != nil {
+			return err
+		}
+		for _, bound := range []ast.Expression{n.Start, n.End} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			err = c.callBack(bound)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSlice)
 	case *ast.AssigExpression:
 		return c.Assig(n)
 	case *ast.FunExpression:
-		return c.fun(n)
+		return c.fun(n, "")
 	case *ast.ReturnStatement:
+		if n.Value == nil {
+			if err := c.exitTries(0); err != nil {
+				return err
+			}
+			c.emit(code.OpReturn)
+			return nil
+		}
 		err := c.callBack(n.Value)
 		if err != nil {
 			return err
 		}
+		if err := c.exitTries(0); err != nil {
+			return err
+		}
 		c.emit(code.OpReturnValue)
+	case *ast.TryStatement:
+		return c.tryStatement(n)
+	case *ast.ThrowStatement:
+		err := c.callBack(n.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
 	case *ast.CallExpression:
+		if hasSpread(n.Params) {
+			return c.spreadCall(n)
+		}
+		//命名参数的值放在普通参数之后, 名字作为常量交给 VM 按参数名绑定
+		var names []object.Object
 		for _, param := range n.Params {
+			if named, ok := param.(*ast.NamedArgument); ok {
+				names = append(names, &object.String{Value: named.Name.Value})
+				param = named.Value
+			}
 			err := c.callBack(param)
 			if err != nil {
 				return err
@@ -127,16 +208,38 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
+		if len(names) > 0 {
+			c.emit(code.OpCallNamed, len(n.Params), c.addConstant(&object.Array{Value: names}))
+			return nil
+		}
 		c.emit(code.OpCall, len(n.Params))
 	case *ast.ForExpression:
 		return c.forExpression(n)
+	case *ast.BreakStatement:
+		loop, err := c.findLoop(n.Label, n.Token)
+		if err != nil {
+			return err
+		}
+		if err := c.exitTries(loop.tries); err != nil {
+			return err
+		}
+		loop.breaks = append(loop.breaks, c.emit(code.OpJump, 999))
+	case *ast.ContinueStatement:
+		loop, err := c.findLoop(n.Label, n.Token)
+		if err != nil {
+			return err
+		}
+		if err := c.exitTries(loop.tries); err != nil {
+			return err
+		}
+		loop.continues = append(loop.continues, c.emit(code.OpJump, 999))
 	case *ast.SuffixExpression:
 		symbol, ok := c.symbolTable.GetSymbol(n.Left.Value)
 		if !ok {
-			return errors.New(fmt.Sprintf("不能对没有定义的变量赋值 %s", n.Left.Value))
+			return errors.New(fmt.Sprintf("%s: 不能对没有定义的变量赋值 %s", n.Pos(), n.Left.Value))
 		}
 		c.symbolEmitGet(symbol)
-		err := c.infixOperator(n.Token.Type)
+		err := c.infixOperator(n.Token)
 		c.symbolEmitSet(symbol)
 		return err
 	}
@@ -151,6 +254,9 @@ func (c *Compiler) callBack(node ast.Node) error {
 	return nil
 }
 func (c *Compiler) infixExpression(infix *ast.InfixExpression) error {
+	if infix.Token.Type == token.AND || infix.Token.Type == token.OR {
+		return c.logicalExpression(infix)
+	}
 	err := c.Compile(infix.Left)
 	if err != nil {
 		return err
@@ -159,9 +265,51 @@ func (c *Compiler) infixExpression(infix *ast.InfixExpression) error {
 	if err != nil {
 		return err
 	}
-	err = c.infixOperator(infix.Token.Type)
+	err = c.infixOperator(infix.Token)
 	return err
 }
+
+// logicalExpression 编译短路的 && 与 ||, 结果总是 bool
+func (c *Compiler) logicalExpression(infix *ast.InfixExpression) error {
+	//a && b:              a || b:
+	//  a                    a
+	//  OpJumpNotTrueThy F   OpJumpNotTrueThy R
+	//  b                    OpTrue
+	//  OpJumpNotTrueThy F   OpJump E
+	//  OpTrue             R:b
+	//  OpJump E             OpJumpNotTrueThy F
+	//F:OpFalse              OpTrue
+	//E:...                  OpJump E
+	//                     F:OpFalse
+	//                     E:...
+	err := c.Compile(infix.Left)
+	if err != nil {
+		return err
+	}
+	var ends []int
+	leftJump := c.emit(code.OpJumpNotTrueThy, 999)
+	if infix.Token.Type == token.OR {
+		c.emit(code.OpTrue)
+		ends = append(ends, c.emit(code.OpJump, 999))
+		c.changOperand(leftJump, len(c.currentInstructions()))
+	}
+	err = c.Compile(infix.Right)
+	if err != nil {
+		return err
+	}
+	rightJump := c.emit(code.OpJumpNotTrueThy, 999)
+	c.emit(code.OpTrue)
+	ends = append(ends, c.emit(code.OpJump, 999))
+	falsePos := c.emit(code.OpFalse)
+	if infix.Token.Type == token.AND {
+		c.changOperand(leftJump, falsePos)
+	}
+	c.changOperand(rightJump, falsePos)
+	for _, pos := range ends {
+		c.changOperand(pos, len(c.currentInstructions()))
+	}
+	return nil
+}
 func (c *Compiler) IntegerLiteral(integer *ast.IntegerLiteral) {
 	obj := &object.Integer{Value: integer.Value}
 	c.emit(code.OpConstant, c.addConstant(obj))
@@ -170,10 +318,12 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.Make(op, operands...)
 	pos := c.addInstruction(ins)
 	c.setEmitted(op, pos)
+	scope := c.scopes[c.scopeIndex]
+	scope.lines = scope.lines.Add(pos, c.position)
 	return pos
 }
 func (c *Compiler) setEmitted(op code.Opcode, pos int) {
-	prev := c.scopes[c.scopeIndex].previous
+	prev := c.scopes[c.scopeIndex].last
 	c.scopes[c.scopeIndex].last = EmittedInstruction{Op: op, Pos: pos}
 	c.scopes[c.scopeIndex].previous = prev
 }
@@ -189,10 +339,11 @@ func (c *Compiler) delLastPop() {
 	old := c.currentInstructions()
 
 	c.scopes[c.scopeIndex].instructions = old[:last.Pos]
+	c.scopes[c.scopeIndex].lines = c.scopes[c.scopeIndex].lines.Truncate(last.Pos)
 	c.scopes[c.scopeIndex].last = previous
 }
-func (c *Compiler) infixOperator(tok token.Type) error {
-	switch tok {
+func (c *Compiler) infixOperator(tok token.Token) error {
+	switch tok.Type {
 	case token.PLUS:
 		c.emit(code.OpAdd)
 	case token.SLASH:
@@ -209,12 +360,28 @@ func (c *Compiler) infixOperator(tok token.Type) error {
 		c.emit(code.OpGT)
 	case token.LT:
 		c.emit(code.OpLT)
+	case token.GtEq:
+		c.emit(code.OpGTE)
+	case token.LtEq:
+		c.emit(code.OpLTE)
+	case token.PERCENT:
+		c.emit(code.OpMod)
+	case token.BitAnd:
+		c.emit(code.OpBitAnd)
+	case token.BitOr:
+		c.emit(code.OpBitOr)
+	case token.BitXor:
+		c.emit(code.OpBitXor)
+	case token.ShiftLeft:
+		c.emit(code.OpShl)
+	case token.ShiftRight:
+		c.emit(code.OpShr)
 	case token.TwoPlus:
 		c.emit(code.OpTwoAdd)
 	case token.TwoMinus:
 		c.emit(code.OpTwoSub)
 	default:
-		return errors.New(fmt.Sprintf("unknown operator %s", tok.ToString()))
+		return errors.New(fmt.Sprintf("%s: unknown operator %s", tok.Pos, tok.Type.ToString()))
 	}
 	return nil
 }
@@ -231,16 +398,19 @@ func (c *Compiler) ByteCode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		Lines:        c.scopes[c.scopeIndex].lines,
 	}
 }
-func (c *Compiler) prefixExpression(tok token.Type) error {
-	switch tok {
+func (c *Compiler) prefixExpression(tok token.Token) error {
+	switch tok.Type {
 	case token.BANG:
 		c.emit(code.OpBang)
 	case token.MINUS:
 		c.emit(code.OpMinus)
+	case token.TILDE:
+		c.emit(code.OpBitNot)
 	default:
-		return errors.New(fmt.Sprintf("unknown operator %s", tok.ToString()))
+		return errors.New(fmt.Sprintf("%s: unknown operator %s", tok.Pos, tok.Type.ToString()))
 	}
 	return nil
 }
@@ -253,33 +423,37 @@ func (c *Compiler) ifExpression(if_ *ast.IFExpression) error {
 		return err
 	}
 	jumpNotPos := c.emit(code.OpJumpNotTrueThy, 999)
-	err = c.callBack(if_.Consequence) //生成ture 语法
+	err = c.blockValue(if_.Consequence) //生成ture 语法
 	if err != nil {
 		return err
 	}
-
-	if c.lastInstructionIs(code.OpPop) {
-		c.delLastPop()
-	}
+	pos := c.emit(code.OpJump, 999)
 	c.changOperand(jumpNotPos, len(c.currentInstructions()))
 	if if_.Alternative != nil {
-		pos := c.emit(code.OpJump, 999)
-		c.changOperand(jumpNotPos, len(c.currentInstructions()))
-
-		err = c.callBack(if_.Alternative)
+		err = c.blockValue(if_.Alternative)
 		if err != nil {
 			return err
 		}
-		if c.lastInstructionIs(code.OpPop) {
-			c.delLastPop()
-		}
-		c.changOperand(pos, len(c.currentInstructions()))
 	} else {
 		//平栈
 		c.emit(code.OpNull)
 	}
+	c.changOperand(pos, len(c.currentInstructions()))
+	return nil
+}
 
-	return err
+// blockValue 编译作为表达式使用的语法块, 保证执行后栈上恰好留下一个值
+func (c *Compiler) blockValue(block *ast.BlockStatement) error {
+	err := c.callBack(block)
+	if err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) {
+		c.delLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
 }
 func (c *Compiler) changOperand(opPos, operand int) {
 	op := code.Opcode(c.currentInstructions()[opPos])
@@ -298,43 +472,69 @@ func (c *Compiler) enterScope() {
 	c.scopes = append(c.scopes, &CompilationScope{})
 	c.scopeIndex++
 }
-func (c *Compiler) leaveScope() code.Instructions {
-	ins := c.currentInstructions()
+func (c *Compiler) leaveScope() (code.Instructions, code.LineTable) {
+	scope := c.scopes[c.scopeIndex]
 	c.scopes = c.scopes[:c.scopeIndex]
 	c.scopeIndex--
-	return ins
+	return scope.instructions, scope.lines
 }
 func (c *Compiler) createScope() {
 	c.scopes = append(c.scopes, &CompilationScope{})
 }
-func (c *Compiler) fun(fun_ *ast.FunExpression) error {
+
+// fun 编译函数, name 为匿名函数绑定的变量名, 用于错误追踪
+func (c *Compiler) fun(fun_ *ast.FunExpression, name string) error {
 	var tmpTSymbol *Symbol
 	if fun_.Name != nil {
-		tmpTSymbol = c.symbolTable.SetSymbol(fun_.Name.Value)
+		tmpTSymbol = c.symbolTable.DefineSymbol(fun_.Name.Value)
+		tmpTSymbol.pending = false
+		name = fun_.Name.Value
 	}
 	symbol := NewSymbolTable(c.symbolTable)
 	c.enterScope() //开启新的作用域
 	c.symbolTable = symbol
 
+	//参数由 VM 在调用时依次放入局部变量
 	for _, param := range fun_.Params {
-		sy := c.symbolTable.SetSymbol(param.Value)
-		c.emit(code.OpSetLocal, sy.index)
+		c.symbolTable.SetSymbol(param.Value)
+	}
+	if fun_.Rest != nil {
+		c.symbolTable.SetSymbol(fun_.Rest.Value)
 	}
-	err := c.callBack(fun_.Block)
+	defaults, err := c.defaultParams(fun_)
 	if err != nil {
 		return err
 	}
-	if c.lastInstructionIs(code.OpPop) {
+	c.declare(fun_.Block.Statements)
+	err = c.callBack(fun_.Block)
+	if err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) && endsWithExpression(fun_.Block) {
 		c.replaceLastPosWithReturn()
 	}
-	ins := c.leaveScope() //恢复作用域
-	fmt.Println("fun")
-	fmt.Println(ins.String())
-	c.symbolTable = symbol.top
-	for _, free := range symbol.free {
-		c.symbolEmitGet(free)
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		//最后一条语句没有值, 返回 null
+		c.emit(code.OpReturn)
 	}
-	c.emit(code.OpLoadFun, c.addConstant(&object.CompliedFun{Instructions: ins, NumLocal: symbol.index}), len(symbol.free))
+	ins, lines := c.leaveScope() //恢复作用域
+	c.symbolTable = symbol.top
+	freeVars := make([]object.FreeVar, len(symbol.free))
+	for i, free := range symbol.free {
+		freeVars[i] = object.FreeVar{Local: free.types == Local, Index: free.index}
+	}
+	fn := &object.CompliedFun{
+		Instructions: ins,
+		FreeVars:     freeVars,
+		NumLocal:     symbol.index,
+		NumParams:    len(fun_.Params),
+		ParamNames:   paramNames(fun_.Params),
+		NumDefaults:  defaults,
+		Variadic:     fun_.Rest != nil,
+		Name:         name,
+		Lines:        lines,
+	}
+	c.emit(code.OpLoadFun, c.addConstant(fn), len(freeVars))
 	if fun_.Name != nil {
 		if tmpTSymbol.types == Global {
 			c.emit(code.OpSetGlobal, tmpTSymbol.index)
@@ -344,20 +544,110 @@ func (c *Compiler) fun(fun_ *ast.FunExpression) error {
 	}
 	return nil
 }
+
+func paramNames(params []*ast.Identifier) []string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Value
+	}
+	return names
+}
+
+// defaultParams 为有默认值的参数生成代码, 调用时已传入该参数则跳过
+func (c *Compiler) defaultParams(fun_ *ast.FunExpression) (int, error) {
+	defaults := 0
+	for i, value := range fun_.Defaults {
+		if value == nil {
+			continue
+		}
+		defaults++
+		pos := c.emit(code.OpDefaultArg, i, 9999)
+		err := c.callBack(value)
+		if err != nil {
+			return 0, err
+		}
+		c.emit(code.OpSetLocal, i)
+		c.replaceInstruction(pos, code.Make(code.OpDefaultArg, i, len(c.currentInstructions())))
+	}
+	return defaults, nil
+}
+
+// declare 提前声明语法块中的具名函数与值为函数的 let, 使函数可以互相递归调用
+// 其他 let 不提前声明, 定义之前读取与解释器一样是未定义的变量
+func (c *Compiler) declare(statements []ast.Statement) {
+	for _, statement := range statements {
+		switch s := statement.(type) {
+		case *ast.LetStatement:
+			if _, ok := s.Value.(*ast.FunExpression); ok {
+				c.symbolTable.Hoist(s.Name.Value)
+			}
+		case *ast.ExpressionStatement:
+			if f, ok := s.Expression.(*ast.FunExpression); ok && f.Name != nil {
+				c.symbolTable.Hoist(f.Name.Value)
+			}
+		}
+	}
+}
+func hasSpread(params []ast.Expression) bool {
+	for _, param := range params {
+		if _, ok := param.(*ast.SpreadExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// spreadCall 编译带有 ...arr 的调用, 相邻的普通参数合并为一个数组, VM 调用时把所有数组拼接为参数列表
+func (c *Compiler) spreadCall(call *ast.CallExpression) error {
+	parts, pending := 0, 0
+	for _, param := range call.Params {
+		spread, ok := param.(*ast.SpreadExpression)
+		if !ok {
+			if err := c.callBack(param); err != nil {
+				return err
+			}
+			pending++
+			continue
+		}
+		if pending > 0 {
+			c.emit(code.OpArray, pending)
+			parts++
+			pending = 0
+		}
+		if err := c.callBack(spread.Value); err != nil {
+			return err
+		}
+		parts++
+	}
+	if pending > 0 {
+		c.emit(code.OpArray, pending)
+		parts++
+	}
+	if err := c.callBack(call.Fun); err != nil {
+		return err
+	}
+	c.emit(code.OpCallSpread, parts)
+	return nil
+}
 func (c *Compiler) replaceLastPosWithReturn() {
 	pos := c.scopes[c.scopeIndex].last.Pos
 
-	c.replaceInstruction(pos, code.Make(code.OpReturn))
-	c.scopes[c.scopeIndex].last.Op = code.OpReturn
+	c.replaceInstruction(pos, code.Make(code.OpReturnValue))
+	c.scopes[c.scopeIndex].last.Op = code.OpReturnValue
 }
 func (c *Compiler) WherePop(exp ast.Expression) {
-	switch exp.(type) {
+	switch e := exp.(type) {
 	//case *ast.CallExpression:
 	//	return
 	case *ast.AssigExpression:
 		return
-	case *ast.FunExpression:
+	case *ast.SuffixExpression:
 		return
+	case *ast.FunExpression:
+		if e.Name != nil {
+			return
+		}
+		c.emit(code.OpPop)
 	case *ast.ForExpression:
 		return
 	default:
@@ -374,10 +664,6 @@ func (c *Compiler) symbolEmitGet(symbol *Symbol) int {
 	case Free:
 		pos = c.emit(code.OpGetFree, symbol.index)
 	}
-	if c.getEmitPos == true {
-		c.pos = pos
-		c.getEmitPos = false
-	}
 	return pos
 }
 func (c *Compiler) symbolEmitSet(symbol *Symbol) int {
@@ -386,6 +672,8 @@ func (c *Compiler) symbolEmitSet(symbol *Symbol) int {
 		return c.emit(code.OpSetGlobal, symbol.index)
 	case Local:
 		return c.emit(code.OpSetLocal, symbol.index)
+	case Free:
+		return c.emit(code.OpSetFree, symbol.index)
 	}
 	return -1
 }
@@ -399,62 +687,80 @@ func (c *Compiler) symbolEmitDel(symbol *Symbol) int {
 	return -1
 }
 func (c *Compiler) forExpression(for_ *ast.ForExpression) error {
-	//0 let a = 0  //这个位置 //get 声明 //
-	//1  get
-	//2 a < 10
-	//3 OpJumpNotTrueThy 7
-	//4 a++  //set opset
-	//5 block
-	//6 OpJump 1
-	//7 ...
+	//0 let a = 0
+	//1 a < 10           <- start
+	//2 OpJumpNotTrueThy 6
+	//3 block            break -> 6
+	//4 a++              <- continue
+	//5 OpJump 1
+	//6 ...
+	//循环变量和循环体中 let 声明的变量只在循环中可见
 
+	c.symbolTable.EnterBlock()
+	defer c.symbolTable.LeaveBlock()
 	err := c.callBack(for_.Left)
 	if err != nil {
 		return err
 	}
-
-	//LetSymbol, ok := c.symbolTable.GetSymbol(for_.Left.Name.Value)
-	//if !ok {
-	//	return errors.New(fmt.Sprintf("不能引用没有定义的变量 %s", for_.Left.Name.Value))
-	//}
-	//getIndex := c.symbolEmitGet(LetSymbol)
-	c.EmitGetPos()
+	start := len(c.currentInstructions())
 	err = c.callBack(for_.Mid)
-	jumpIndex, errs := c.getEmitPos_()
-	if errs != nil {
-		return err
-	}
 	if err != nil {
 		return err
 	}
 	index := c.emit(code.OpJumpNotTrueThy, 999)
+
+	loop := c.enterLoop(for_.Label)
 	err = c.callBack(for_.Block)
+	c.leaveLoop()
 	if err != nil {
 		return err
 	}
+	continuePos := len(c.currentInstructions())
 	err = c.callBack(for_.Right)
 	if err != nil {
 		return err
 	}
-	c.emit(code.OpJump, jumpIndex)
-	if c.lastInstructionIs(code.OpPop) {
-		c.delLastPop()
+	c.emit(code.OpJump, start)
+
+	end := len(c.currentInstructions())
+	c.changOperand(index, end)
+	for _, pos := range loop.breaks {
+		c.changOperand(pos, end)
+	}
+	for _, pos := range loop.continues {
+		c.changOperand(pos, continuePos)
 	}
-	c.changOperand(index, len(c.currentInstructions()))
-	//c.symbolEmitDel(LetSymbol)
-	c.symbolTable.DelSymbol(for_.Left.Name.Value)
 	return nil
 }
-func (c *Compiler) EmitGetPos() {
-	c.getEmitPos = true
+func (c *Compiler) enterLoop(label *ast.Identifier) *loopContext {
+	scope := c.scopes[c.scopeIndex]
+	loop := &loopContext{tries: len(scope.tries)}
+	if label != nil {
+		loop.label = label.Value
+	}
+	scope.loops = append(scope.loops, loop)
+	return loop
+}
+func (c *Compiler) leaveLoop() {
+	scope := c.scopes[c.scopeIndex]
+	scope.loops = scope.loops[:len(scope.loops)-1]
 }
-func (c *Compiler) getEmitPos_() (int, error) {
-	if c.pos > -1 {
-		tmp := c.pos
-		c.pos = tmp
-		return c.pos, nil
+
+// findLoop 查找 break/continue 所属的循环, label 为空时返回最内层循环
+func (c *Compiler) findLoop(label *ast.Identifier, tok token.Token) (*loopContext, error) {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil, errors.New(fmt.Sprintf("%s: %s 只能在循环中使用", tok.Pos, tok.Literal))
+	}
+	if label == nil {
+		return loops[len(loops)-1], nil
+	}
+	for i := len(loops) - 1; i >= 0; i-- {
+		if loops[i].label == label.Value {
+			return loops[i], nil
+		}
 	}
-	return -1, errors.New("回退位置失败")
+	return nil, errors.New(fmt.Sprintf("%s: 未定义的标签 %s", label.Pos(), label.Value))
 }
 func (c *Compiler) Assig(n *ast.AssigExpression) error {
 	//普通赋值
@@ -470,10 +776,13 @@ func (c *Compiler) Assig(n *ast.AssigExpression) error {
 }
 func (c *Compiler) AssigArray(node *ast.AssigExpression) error {
 	indexNode := node.Name.(*ast.IndexExpression)
-	name := indexNode.Left.(*ast.Identifier)
+	name, ok := indexNode.Left.(*ast.Identifier)
+	if !ok {
+		return c.assigIndex(node.Value, indexNode)
+	}
 	symbol, ok := c.symbolTable.GetSymbol(name.Value)
 	if !ok {
-		return errors.New(fmt.Sprintf("不能对一个没有声明的变量赋值 %s", name))
+		return errors.New(fmt.Sprintf("%s: 不能对一个没有声明的变量赋值 %s", name.Pos(), name))
 	}
 	err := c.callBack(node.Value)
 	if err != nil {
@@ -481,20 +790,41 @@ func (c *Compiler) AssigArray(node *ast.AssigExpression) error {
 	}
 	err = c.callBack(indexNode.Index)
 	if err != nil {
-		return nil
+		return err
 	}
-	if symbol.types == Global {
+	switch symbol.types {
+	case Global:
 		c.emit(code.OpSetIndexGlobal, symbol.index)
-	} else {
+	case Local:
 		c.emit(code.OpSetIndexLocal, symbol.index)
+	case Free:
+		c.emit(code.OpSetIndexFree, symbol.index)
 	}
 	return nil
 }
+
+// assigIndex a.b.c = v 与 a[0][1] = v 这类左边不是变量的赋值, 计算出容器后用 OpSetIndex 修改
+func (c *Compiler) assigIndex(value ast.Expression, indexNode *ast.IndexExpression) error {
+	err := c.callBack(value)
+	if err != nil {
+		return err
+	}
+	err = c.callBack(indexNode.Left)
+	if err != nil {
+		return err
+	}
+	err = c.callBack(indexNode.Index)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpSetIndex)
+	return nil
+}
 func (c *Compiler) AssigOrdinary(node *ast.AssigExpression) error {
 	name := node.Name.(*ast.Identifier)
 	symbol, ok := c.symbolTable.GetSymbol(name.Value)
-	if !ok {
-		return errors.New(fmt.Sprintf("不能对一个没有声明的变量赋值 %s", name))
+	if !ok || c.symbolTable.Uninitialised(name.Value) {
+		return errors.New(fmt.Sprintf("%s: 不能对一个没有声明的变量赋值 %s", name.Pos(), name))
 	}
 	err := c.callBack(node.Value)
 	if err != nil {
@@ -503,3 +833,113 @@ func (c *Compiler) AssigOrdinary(node *ast.AssigExpression) error {
 	c.symbolEmitSet(symbol)
 	return nil
 }
+
+// endsWithExpression 函数体最后一条语句是表达式时, 它的值作为返回值
+func endsWithExpression(block *ast.BlockStatement) bool {
+	if len(block.Statements) == 0 {
+		return false
+	}
+	_, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement)
+	return ok
+}
+
+// tryStatement 编译 try 语句, finally 在正常结束与抛出错误两条路径上各生成一份
+//
+//	    OpTry catch
+//	    try 语法块
+//	    OpPopHandler
+//	    OpJump finally
+//	catch:
+//	    OpTry rethrow      有 finally 时, catch 中抛出的错误也要先执行 finally
+//	    OpSetXxx e
+//	    catch 语法块
+//	    OpPopHandler
+//	finally:
+//	    finally 语法块
+//	    OpJump end
+//	rethrow:
+//	    finally 语法块
+//	    OpThrow
+//	end:
+func (c *Compiler) tryStatement(try *ast.TryStatement) error {
+	var rethrows []int
+	tryPos := c.emit(code.OpTry, 9999)
+	c.enterTry(try.Finally)
+	err := c.callBack(try.Block)
+	c.leaveTry()
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpPopHandler)
+	finallyPos := c.emit(code.OpJump, 9999)
+
+	if try.Catch != nil {
+		c.changOperand(tryPos, len(c.currentInstructions()))
+		if try.Finally != nil {
+			rethrows = append(rethrows, c.emit(code.OpTry, 9999))
+			c.enterTry(try.Finally)
+		}
+		if try.Param != nil {
+			c.symbolEmitSet(c.symbolTable.DefineSymbol(try.Param.Value))
+		} else {
+			c.emit(code.OpPop)
+		}
+		err = c.callBack(try.Catch)
+		if try.Finally != nil {
+			c.leaveTry()
+			c.emit(code.OpPopHandler)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		rethrows = append(rethrows, tryPos)
+	}
+	c.changOperand(finallyPos, len(c.currentInstructions()))
+	if try.Finally == nil {
+		return nil
+	}
+	err = c.callBack(try.Finally)
+	if err != nil {
+		return err
+	}
+	endPos := c.emit(code.OpJump, 9999)
+	for _, pos := range rethrows {
+		c.changOperand(pos, len(c.currentInstructions()))
+	}
+	err = c.callBack(try.Finally)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpThrow)
+	c.changOperand(endPos, len(c.currentInstructions()))
+	return nil
+}
+func (c *Compiler) enterTry(finally *ast.BlockStatement) {
+	scope := c.scopes[c.scopeIndex]
+	scope.tries = append(scope.tries, &tryContext{finally: finally})
+}
+func (c *Compiler) leaveTry() {
+	scope := c.scopes[c.scopeIndex]
+	scope.tries = scope.tries[:len(scope.tries)-1]
+}
+
+// exitTries 从 try 中 return break continue 之前, 由内向外弹出 handler 并执行 finally, 保留外层的 keep 个 try
+func (c *Compiler) exitTries(keep int) error {
+	scope := c.scopes[c.scopeIndex]
+	tries := scope.tries
+	defer func() {
+		scope.tries = tries
+	}()
+	for i := len(tries) - 1; i >= keep; i-- {
+		scope.tries = tries[:i]
+		c.emit(code.OpPopHandler)
+		if tries[i].finally == nil {
+			continue
+		}
+		if err := c.callBack(tries[i].finally); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -8,21 +8,29 @@ const (
 
 type SymbolType int
 type SymbolTable struct {
-	top   *SymbolTable
-	table map[string]*Symbol
-	index int
-	free  []*Symbol
+	top    *SymbolTable
+	table  map[string]*Symbol
+	index  int
+	free   []*Symbol
+	blocks []map[string]*Symbol //块作用域, 记录块中声明的变量遮住的外层变量, 外层没有该变量时为 nil
 }
 type Symbol struct {
-	index int
-	Name  string
-	types SymbolType
+	index   int
+	Name    string
+	types   SymbolType
+	pending bool //已经提前声明, 但还没有执行到 let 或函数定义
 }
 
 func NewSymbolTable(top *SymbolTable) *SymbolTable {
 	return &SymbolTable{table: map[string]*Symbol{}, top: top}
 }
 func (s *SymbolTable) SetSymbol(name string) *Symbol {
+	if len(s.blocks) > 0 {
+		block := s.blocks[len(s.blocks)-1]
+		if _, ok := block[name]; !ok {
+			block[name] = s.table[name]
+		}
+	}
 	symbol := &Symbol{Name: name, index: s.index}
 	if s.top == nil {
 		symbol.types = Global
@@ -33,6 +41,62 @@ func (s *SymbolTable) SetSymbol(name string) *Symbol {
 	s.index++
 	return symbol
 }
+
+// DefineSymbol 返回当前作用域中已经声明的变量, 没有时新建, 提前声明过的变量不会重复分配
+func (s *SymbolTable) DefineSymbol(name string) *Symbol {
+	if v, ok := s.declared(name); ok {
+		return v
+	}
+	return s.SetSymbol(name)
+}
+
+// Hoist 提前声明变量, 在定义之前同一作用域中不能使用它, 内部函数可以引用
+func (s *SymbolTable) Hoist(name string) *Symbol {
+	if v, ok := s.declared(name); ok {
+		return v
+	}
+	symbol := s.SetSymbol(name)
+	symbol.pending = true
+	return symbol
+}
+
+// declared 返回当前块中已经声明的变量, 不在块中时为整个作用域
+func (s *SymbolTable) declared(name string) (*Symbol, bool) {
+	v, ok := s.table[name]
+	if !ok || v.types == Free {
+		return nil, false
+	}
+	if len(s.blocks) > 0 {
+		if _, ok := s.blocks[len(s.blocks)-1][name]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// EnterBlock 进入块作用域, 块中声明的变量会遮住外层的同名变量, 槽位不回收
+func (s *SymbolTable) EnterBlock() {
+	s.blocks = append(s.blocks, map[string]*Symbol{})
+}
+
+// LeaveBlock 离开块作用域, 恢复被遮住的外层变量
+func (s *SymbolTable) LeaveBlock() {
+	block := s.blocks[len(s.blocks)-1]
+	s.blocks = s.blocks[:len(s.blocks)-1]
+	for name, symbol := range block {
+		if symbol == nil {
+			delete(s.table, name)
+			continue
+		}
+		s.table[name] = symbol
+	}
+}
+
+// Uninitialised 变量在当前作用域中声明了, 但还没有执行到定义
+func (s *SymbolTable) Uninitialised(name string) bool {
+	v, ok := s.table[name]
+	return ok && v.pending
+}
 func (s *SymbolTable) GetSymbol(name string) (*Symbol, bool) {
 	v, ok := s.table[name]
 	if !ok && s.top != nil {
@@ -57,6 +121,3 @@ func (s *SymbolTable) setFreeSymbol(symbol *Symbol) *Symbol {
 	s.table[symbol.Name] = sy
 	return sy
 }
-func (s *SymbolTable) DelSymbol(name string) {
-	delete(s.table, name)
-}
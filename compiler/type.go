@@ -1,8 +1,10 @@
 package compiler
 
 import (
+	"hek/ast"
 	"hek/code"
 	"hek/object"
+	"hek/token"
 )
 
 type EmittedInstruction struct {
@@ -13,6 +15,22 @@ type CompilationScope struct {
 	instructions code.Instructions
 	last         EmittedInstruction
 	previous     EmittedInstruction
+	lines        code.LineTable
+	loops        []*loopContext //当前函数内正在编译的循环, 最内层在最后
+	tries        []*tryContext  //当前函数内安装了 handler 的 try 语句, 最内层在最后
+}
+
+// loopContext 记录循环中需要回填跳转地址的 break/continue
+type loopContext struct {
+	label     string
+	breaks    []int
+	continues []int
+	tries     int //进入循环时 tries 的数量, break/continue 需要离开之后的 try
+}
+
+// tryContext return break continue 离开 try 时需要弹出 handler 并执行 finally
+type tryContext struct {
+	finally *ast.BlockStatement
 }
 type Compiler struct {
 	constants   []object.Object
@@ -20,10 +38,10 @@ type Compiler struct {
 	scopeIndex  int
 	symbolTable *SymbolTable
 
-	getEmitPos bool
-	pos        int
+	position token.Position //当前编译节点的源码位置
 }
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	Lines        code.LineTable
 }
@@ -0,0 +1,79 @@
+package lexer
+
+import "strings"
+
+// skipShebang 跳过脚本首行的 #! 解释器声明
+func (l *Lexer) skipShebang() {
+	if l.ch != '#' || l.peekChar() != '!' {
+		return
+	}
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// skipSpaceAndComment 跳过空白与注释, 返回紧贴在下一个 token 之前的注释内容(文档注释).
+// 注释与 token 之间有空行, 或注释跟在上一个 token 的同一行时不作为文档注释.
+// 块注释没有结束时 ok 为 false
+func (l *Lexer) skipSpaceAndComment() (doc string, ok bool) {
+	var lines []string
+	newlines := 0
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '\n':
+			newlines++
+			if newlines > 1 {
+				lines = nil
+			}
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			trailing := l.line == l.prevLine
+			text := l.readLineComment()
+			if !trailing {
+				lines = append(lines, text)
+			}
+			newlines = 0
+		case l.ch == '/' && l.peekChar() == '*':
+			trailing := l.line == l.prevLine
+			text, closed := l.readBlockComment()
+			if !closed {
+				return "", false
+			}
+			if !trailing {
+				lines = append(lines, text)
+			}
+			newlines = 0
+		default:
+			return strings.Join(lines, "\n"), true
+		}
+	}
+}
+func (l *Lexer) readLineComment() string {
+	position := l.position + 2
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	return strings.TrimSpace(l.input[position:l.position])
+}
+func (l *Lexer) readBlockComment() (string, bool) {
+	l.readChar()
+	l.readChar()
+	position := l.position
+	for !(l.ch == '*' && l.peekChar() == '/') {
+		if l.ch == 0 {
+			return "", false
+		}
+		l.readChar()
+	}
+	text := l.input[position:l.position]
+	l.readChar()
+	l.readChar()
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimSpace(line), "* ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), true
+}
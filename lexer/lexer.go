@@ -1,41 +1,89 @@
 package lexer
 
 import (
-	"bytes"
+	"fmt"
 	"hek/token"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Lexer struct {
 	input        string
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune
+
+	file     string
+	line     int
+	column   int
+	prevLine int //上一个 token 结束时所在的行
+
+	errors []string
+	interp []int //正在读取的 ${ } 插值表达式中未闭合的 { 数量, 支持嵌套
 }
 
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewLexerFile("", input)
+}
+
+// NewLexerFile 创建词法分析器, file 用于记录 token 所在的文件
+func NewLexerFile(file, input string) *Lexer {
+	l := &Lexer{input: input, file: file, line: 1}
 	l.readChar()
+	l.skipShebang()
 	return l
 }
-func newToken(types token.Type, ch byte) token.Token {
+
+// Errors 返回词法错误, 每个错误对应一个 ILLEGAL token
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+func (l *Lexer) errorf(pos token.Position, format string, args ...interface{}) {
+	l.errors = append(l.errors, pos.String()+": "+fmt.Sprintf(format, args...))
+}
+func newToken(types token.Type, ch rune) token.Token {
 	return token.Token{
 		Type:    types,
 		Literal: string(ch),
 	}
 }
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+		l.position = len(l.input)
+		l.readPosition = len(l.input)
+		return
 	}
+	ch, size := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = ch
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += size
+}
+func (l *Lexer) pos() token.Position {
+	return token.Position{File: l.file, Line: l.line, Column: l.column}
 }
 func (l *Lexer) NextToke() token.Token {
+	doc, ok := l.skipSpaceAndComment()
+	pos := l.pos()
+	var tok token.Token
+	if ok {
+		tok = l.nextToken()
+	} else {
+		l.errorf(pos, "unterminated comment")
+		tok = token.Token{Type: token.ILLEGAL, Literal: "/*"}
+	}
+	tok.Pos = pos
+	tok.Doc = doc
+	l.prevLine = l.line
+	return tok
+}
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
-
-	l.skipWhitespace()
 
 	switch l.ch {
 	case '=':
@@ -54,8 +102,20 @@ func (l *Lexer) NextToke() token.Token {
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
 	case '{':
+		if len(l.interp) > 0 {
+			l.interp[len(l.interp)-1]++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		if len(l.interp) > 0 {
+			if l.interp[len(l.interp)-1] == 0 {
+				//插值表达式结束, 继续读取字符串
+				l.interp = l.interp[:len(l.interp)-1]
+				tok = l.readString(false)
+				break
+			}
+			l.interp[len(l.interp)-1]--
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
@@ -73,20 +133,67 @@ func (l *Lexer) NextToke() token.Token {
 		} else {
 			tok = newToken(token.MINUS, l.ch)
 		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.BitAnd, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.BitOr, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BitXor, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '/':
 		tok = newToken(token.SLASH, l.ch)
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.LtEq, Literal: "<="}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = token.Token{Type: token.ShiftLeft, Literal: "<<"}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.GtEq, Literal: ">="}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = token.Token{Type: token.ShiftRight, Literal: ">>"}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if l.peekChar() == '.' && l.peekCharAt(2) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else if l.isDigit(l.peekChar()) {
+			num, _ := l.readNumber()
+			return token.Token{Type: token.FLOAT, Literal: num}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -100,9 +207,9 @@ func (l *Lexer) NextToke() token.Token {
 		tok.Literal = ""
 		tok.Type = token.EOF
 	case '"':
-		v := l.readString()
-		tok.Type = token.String
-		tok.Literal = v
+		tok = l.readString(true)
+	case '`':
+		tok = l.readRawString()
 	default:
 		if l.isLetter(l.ch) {
 			identifier := l.readIdentifier()
@@ -110,22 +217,28 @@ func (l *Lexer) NextToke() token.Token {
 			tok.Literal = identifier
 			return tok
 		} else if l.isDigit(l.ch) {
-			num := l.readNumber()
+			num, isFloat := l.readNumber()
 			tok.Type = token.INT
+			if isFloat {
+				tok.Type = token.FLOAT
+			}
 			tok.Literal = num
 			return tok
 		} else {
-			return newToken(token.ILLEGAL, l.ch)
+			l.errorf(l.pos(), "illegal character '%c'", l.ch)
+			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 	l.readChar()
 	return tok
 }
-func (l *Lexer) isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+
+// isLetter 标识符可以使用 unicode 字母, 例如 let 名字 = 1
+func (l *Lexer) isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
-func (l *Lexer) isLetterNum(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || '0' <= ch && ch <= '9'
+func (l *Lexer) isLetterNum(ch rune) bool {
+	return l.isLetter(ch) || '0' <= ch && ch <= '9' || ch >= utf8.RuneSelf && unicode.IsDigit(ch)
 }
 func (l *Lexer) readIdentifier() string {
 	position := l.position
@@ -134,37 +247,53 @@ func (l *Lexer) readIdentifier() string {
 	}
 	return l.input[position:l.position]
 }
-func (l *Lexer) readNumber() string {
+
+// readNumber 读取 12 1.5 .5 1e-3 形式的数字, 有小数部分或指数部分时为 float
+func (l *Lexer) readNumber() (string, bool) {
 	position := l.position
+	isFloat := false
 	for l.isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
-}
-func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' || l.ch == '\n' {
+	if l.ch == '.' && l.isDigit(l.peekChar()) {
+		isFloat = true
 		l.readChar()
+		for l.isDigit(l.ch) {
+			l.readChar()
+		}
 	}
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if l.isDigit(next) || (next == '+' || next == '-') && l.isDigit(l.peekCharAt(2)) {
+			isFloat = true
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			for l.isDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+	return l.input[position:l.position], isFloat
 }
-func (l *Lexer) isDigit(ch byte) bool {
+func (l *Lexer) isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.readPosition]
-	}
+func (l *Lexer) peekChar() rune {
+	return l.peekCharAt(1)
 }
 
-func (l *Lexer) readString() string {
-	var out bytes.Buffer
-	for {
-		l.readChar()
-		if l.ch == '"' {
-			break
-		}
-		out.WriteByte(l.ch)
+// peekCharAt 查看当前字符之后第 n 个字符
+func (l *Lexer) peekCharAt(n int) rune {
+	position := l.readPosition
+	for i := 1; i < n && position < len(l.input); i++ {
+		_, size := utf8.DecodeRuneInString(l.input[position:])
+		position += size
+	}
+	if position >= len(l.input) {
+		return 0
 	}
-	return out.String()
+	ch, _ := utf8.DecodeRuneInString(l.input[position:])
+	return ch
 }
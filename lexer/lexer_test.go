@@ -20,3 +20,150 @@ func TestNewLexer(t *testing.T) {
 		}
 	}
 }
+
+func TestPosition(t *testing.T) {
+	input := "let a = 1;\n  a + 10"
+
+	tests := []token.Position{
+		{File: "t.hek", Line: 1, Column: 1},
+		{File: "t.hek", Line: 1, Column: 5},
+		{File: "t.hek", Line: 1, Column: 7},
+		{File: "t.hek", Line: 1, Column: 9},
+		{File: "t.hek", Line: 1, Column: 10},
+		{File: "t.hek", Line: 2, Column: 3},
+		{File: "t.hek", Line: 2, Column: 5},
+		{File: "t.hek", Line: 2, Column: 7},
+	}
+
+	l := NewLexerFile("t.hek", input)
+	for i, want := range tests {
+		tok := l.NextToke()
+		if tok.Pos != want {
+			t.Errorf("tests[%d] %q: pos %s, want %s", i, tok.Literal, tok.Pos, want)
+		}
+	}
+}
+
+func TestComment(t *testing.T) {
+	input := `#!/usr/bin/env hek
+// 加法
+// 返回 a + b
+fun add(a, b) { a + b } // 行尾注释
+/* 块
+ * 注释 */
+let x = 1 /* 内联 */ / 2;
+
+// 空行之后不是文档注释
+
+let y = 2;
+/* 未结束`
+
+	tests := []struct {
+		typ     token.Type
+		literal string
+		doc     string
+	}{
+		{token.FUNCTION, "fun", "加法\n返回 a + b"},
+		{token.IDENT, "add", ""},
+		{token.LPAREN, "(", ""},
+		{token.IDENT, "a", ""},
+		{token.COMMA, ",", ""},
+		{token.IDENT, "b", ""},
+		{token.RPAREN, ")", ""},
+		{token.LBRACE, "{", ""},
+		{token.IDENT, "a", ""},
+		{token.PLUS, "+", ""},
+		{token.IDENT, "b", ""},
+		{token.RBRACE, "}", ""},
+		{token.LET, "let", "块\n注释"},
+		{token.IDENT, "x", ""},
+		{token.ASSIGN, "=", ""},
+		{token.INT, "1", ""},
+		{token.SLASH, "/", ""},
+		{token.INT, "2", ""},
+		{token.SEMICOLON, ";", ""},
+		{token.LET, "let", ""},
+		{token.IDENT, "y", ""},
+		{token.ASSIGN, "=", ""},
+		{token.INT, "2", ""},
+		{token.SEMICOLON, ";", ""},
+		{token.ILLEGAL, "/*", ""},
+		{token.EOF, "", ""},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToke()
+		if tok.Type != tt.typ || tok.Literal != tt.literal || tok.Doc != tt.doc {
+			t.Fatalf("tests[%d] got %s %q doc %q, want %s %q doc %q", i,
+				tok.Type.ToString(), tok.Literal, tok.Doc, tt.typ.ToString(), tt.literal, tt.doc)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		input   string
+		typ     token.Type
+		literal string
+		err     string
+	}{
+		{`"a\tb\n"`, token.String, "a\tb\n", ""},
+		{`"say \"hi\" \\ \'"`, token.String, `say "hi" \ '`, ""},
+		{`"\u{4f60}\u{597D}\u{1F600}"`, token.String, "你好😀", ""},
+		{"`raw \\n\n\"line\"`", token.String, "raw \\n\n\"line\"", ""},
+		{`"abc`, token.ILLEGAL, `"abc`, "1:1: unterminated string"},
+		{`"a\`, token.ILLEGAL, `"a\`, "1:1: unterminated string"},
+		{"`abc", token.ILLEGAL, "`abc", "1:1: unterminated raw string"},
+		{`"a\qb"`, token.ILLEGAL, `"a\qb"`, `1:3: invalid escape sequence '\q'`},
+		{`"\u{110000}"`, token.ILLEGAL, `"\u{110000}"`, `1:2: invalid unicode code point '\u{110000}'`},
+		{`"\u41"`, token.ILLEGAL, `"\u41"`, `1:2: invalid escape sequence '\u', want \u{XXXX}`},
+		{"@", token.ILLEGAL, "@", "1:1: illegal character '@'"},
+	}
+	for _, tt := range tests {
+		l := NewLexer(tt.input)
+		tok := l.NextToke()
+		if tok.Type != tt.typ || tok.Literal != tt.literal {
+			t.Errorf("%s: got %s %q, want %s %q", tt.input, tok.Type.ToString(), tok.Literal, tt.typ.ToString(), tt.literal)
+		}
+		var err string
+		if len(l.Errors()) > 0 {
+			err = l.Errors()[0]
+		}
+		if err != tt.err {
+			t.Errorf("%s: error %q, want %q", tt.input, err, tt.err)
+		}
+		if next := l.NextToke(); next.Type != token.EOF {
+			t.Errorf("%s: next token %s %q, want EOF", tt.input, next.Type.ToString(), next.Literal)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let 名字 = \"你好\";\nπ2 + 名字"
+
+	tests := []struct {
+		typ     token.Type
+		literal string
+		column  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "名字", 5},
+		{token.ASSIGN, "=", 8},
+		{token.String, "你好", 10},
+		{token.SEMICOLON, ";", 14},
+		{token.IDENT, "π2", 1},
+		{token.PLUS, "+", 4},
+		{token.IDENT, "名字", 6},
+		{token.EOF, "", 8},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToke()
+		if tok.Type != tt.typ || tok.Literal != tt.literal || tok.Pos.Column != tt.column {
+			t.Fatalf("tests[%d] got %s %q col %d, want %s %q col %d", i,
+				tok.Type.ToString(), tok.Literal, tok.Pos.Column, tt.typ.ToString(), tt.literal, tt.column)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package lexer
+
+import (
+	"bytes"
+	"hek/token"
+	"strconv"
+	"unicode/utf8"
+)
+
+// readString 读取双引号字符串, 支持 \n \t \r \0 \\ \" \' \$ \u{XXXX} 转义.
+// 遇到 ${ 时返回插值字符串的一段, head 表示从 " 开始读取, 否则从插值表达式的 } 开始读取.
+// 字符串没有结束或转义错误时返回 ILLEGAL, 结束时 l.ch 停在最后一个字符上
+func (l *Lexer) readString(head bool) token.Token {
+	var out bytes.Buffer
+	start := l.position
+	startPos := l.pos()
+	valid := true
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			l.errorf(startPos, "unterminated string")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start:]}
+		case '"':
+			if !valid {
+				return token.Token{Type: token.ILLEGAL, Literal: l.input[start : l.position+1]}
+			}
+			if head {
+				return token.Token{Type: token.String, Literal: out.String()}
+			}
+			return token.Token{Type: token.StringTail, Literal: out.String()}
+		case '$':
+			if l.peekChar() != '{' {
+				out.WriteRune(l.ch)
+				break
+			}
+			l.readChar()
+			l.interp = append(l.interp, 0)
+			if !valid {
+				return token.Token{Type: token.ILLEGAL, Literal: l.input[start : l.position+1]}
+			}
+			if head {
+				return token.Token{Type: token.StringHead, Literal: out.String()}
+			}
+			return token.Token{Type: token.StringMid, Literal: out.String()}
+		case '\\':
+			if !l.readEscape(&out) {
+				valid = false
+			}
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// readEscape 读取 \ 之后的转义字符, 写入 out
+func (l *Lexer) readEscape(out *bytes.Buffer) bool {
+	pos := l.pos()
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\', '"', '\'', '$':
+		out.WriteRune(l.ch)
+	case 'u':
+		return l.readUnicodeEscape(out, pos)
+	case 0:
+		//交给 readString 报告字符串没有结束
+		return true
+	default:
+		l.errorf(pos, "invalid escape sequence '\\%c'", l.ch)
+		return false
+	}
+	return true
+}
+
+// readUnicodeEscape 读取 \u{XXXX}, 花括号中为 1 到 6 位十六进制
+func (l *Lexer) readUnicodeEscape(out *bytes.Buffer, pos token.Position) bool {
+	if l.peekChar() != '{' {
+		l.errorf(pos, "invalid escape sequence '\\u', want \\u{XXXX}")
+		return false
+	}
+	l.readChar()
+	start := l.position + 1
+	for isHex(l.peekChar()) {
+		l.readChar()
+	}
+	hex := l.input[start : l.position+1]
+	if l.peekChar() != '}' || len(hex) == 0 || len(hex) > 6 {
+		l.errorf(pos, "invalid escape sequence '\\u{%s'", hex)
+		return false
+	}
+	l.readChar()
+	code, _ := strconv.ParseUint(hex, 16, 32)
+	r := rune(code)
+	if !utf8.ValidRune(r) {
+		l.errorf(pos, "invalid unicode code point '\\u{%s}'", hex)
+		return false
+	}
+	out.WriteRune(r)
+	return true
+}
+
+// readRawString 读取反引号字符串, 内容原样保留, 可以跨行
+func (l *Lexer) readRawString() token.Token {
+	start := l.position
+	startPos := l.pos()
+	for {
+		l.readChar()
+		switch l.ch {
+		case 0:
+			l.errorf(startPos, "unterminated raw string")
+			return token.Token{Type: token.ILLEGAL, Literal: l.input[start:]}
+		case '`':
+			return token.Token{Type: token.String, Literal: l.input[start+1 : l.position]}
+		}
+	}
+}
+func isHex(ch rune) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
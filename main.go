@@ -1,10 +1,31 @@
 package main
 
 import (
+	"fmt"
 	"hek/repl"
+	"io"
 	"os"
 )
 
+const usage = "usage: hek [run file.hek [args...]]"
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+	}
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// run 执行 hek run 子命令, 返回退出码: 用法错误为 2, 脚本出错为 1
+func run(args []string, stdout, stderr io.Writer) int {
+	if args[0] != "run" || len(args) < 2 {
+		fmt.Fprintln(stderr, usage)
+		return 2
+	}
+	err := repl.RunFile(args[1], args[2:], stdout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
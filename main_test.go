@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	ok := filepath.Join(dir, "ok.hek")
+	bad := filepath.Join(dir, "bad.hek")
+	if err := os.WriteFile(ok, []byte(`echo(args())`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte(`throw "boom"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr bool
+	}{
+		{[]string{"run", ok, "x", "y"}, 0, "[x,y]\n", false},
+		{[]string{"run", bad}, 1, "", true},
+		{[]string{"run", filepath.Join(dir, "missing.hek")}, 1, "", true},
+		{[]string{"run"}, 2, "", true},
+		{[]string{"exec", ok}, 2, "", true},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(tt.args, &stdout, &stderr); code != tt.code {
+			t.Errorf("%v exit = %d, want %d", tt.args, code, tt.code)
+		}
+		if got := stdout.String(); got != tt.stdout {
+			t.Errorf("%v stdout = %q, want %q", tt.args, got, tt.stdout)
+		}
+		if got := stderr.Len() > 0; got != tt.stderr {
+			t.Errorf("%v stderr = %q", tt.args, stderr.String())
+		}
+	}
+	var stderr bytes.Buffer
+	run([]string{"run"}, &bytes.Buffer{}, &stderr)
+	if got := stderr.String(); got != usage+"\n" {
+		t.Errorf("usage = %q, want %q", got, usage+"\n")
+	}
+}
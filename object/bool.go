@@ -13,3 +13,25 @@ func (b *Bool) Type() ObjectType {
 func (b *Bool) Inspect() string {
 	return fmt.Sprintf("%v", b.Value)
 }
+
+// Truthy 真假判断的唯一规则, if while ! && || filter 与 bool() 都使用它
+// false null 0 0.0 "" [] {} 为假, 其余的值 (包括函数与错误) 都为真
+func Truthy(obj Object) bool {
+	switch o := obj.(type) {
+	case *Bool:
+		return o.Value
+	case *Null:
+		return false
+	case *Integer:
+		return o.Value != 0
+	case *Float:
+		return o.Value != 0
+	case *String:
+		return o.Value != ""
+	case *Array:
+		return len(o.Value) != 0
+	case *Hash:
+		return o.Len() != 0
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package object
+
+import (
+	"io"
+	"os"
+)
+
+// Caller 由执行引擎提供, 使内置函数可以调用 hek 函数
+type Caller func(fn Object, args ...Object) (Object, *Error)
+
+// MaxDepth 默认的最大调用深度, 包含 main, 超过时报 stack overflow, 解释器与 VM 相同
+const MaxDepth = 1024
+
+// Context 内置函数的运行环境, 每个 VM 与解释器各有一份, 同时运行的引擎互不影响
+type Context struct {
+	Call Caller    //回调 hek 函数
+	Out  io.Writer //println 与 echo 的输出位置
+	Args []Object  //args() 返回的脚本参数
+
+	maxDepth int          //解释器的最大调用深度, VM 使用自己的帧计数
+	frames   []TraceFrame //解释器的调用栈, 第一个是 main, 最内层在最后
+}
+
+// NewContext 默认输出到标准输出, 没有脚本参数
+func NewContext(call Caller) *Context {
+	return &Context{Call: call, Out: os.Stdout, maxDepth: MaxDepth}
+}
+
+// trace 返回解释器的调用栈, 与 VM 一样从最内层的帧开始依次到 main
+func (c *Context) trace() []TraceFrame {
+	trace := make([]TraceFrame, len(c.frames))
+	for i, frame := range c.frames {
+		trace[len(c.frames)-1-i] = frame
+	}
+	return trace
+}
+
+// SetArgs 设置脚本运行参数, 通过 args() 获取
+func (c *Context) SetArgs(args []string) {
+	c.Args = make([]Object, len(args))
+	for i, arg := range args {
+		c.Args[i] = &String{Value: arg}
+	}
+}
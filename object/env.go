@@ -3,24 +3,55 @@ package object
 type Env struct {
 	store map[string]Object
 	top   *Env
+	ctx   *Context //内置函数的运行环境, 内层作用域与最外层共用
 }
 
 func NewEnv(envs *Env) *Env {
-	return &Env{store: make(map[string]Object), top: envs}
+	if envs == nil {
+		ctx := NewContext(callEvalFun)
+		ctx.frames = []TraceFrame{{Name: "main", Ip: -1}}
+		return &Env{store: make(map[string]Object), ctx: ctx}
+	}
+	return &Env{store: make(map[string]Object), top: envs, ctx: envs.ctx}
+}
+
+// Context 返回解释器的内置函数运行环境, 可以修改输出位置与脚本参数
+func (r *Env) Context() *Context {
+	return r.ctx
+}
+
+// SetMaxDepth 设置解释器的最大调用深度, 包含 main, 与 VM 的 SetMaxDepth 相同
+func (r *Env) SetMaxDepth(depth int) {
+	r.ctx.maxDepth = depth
 }
 func (r *Env) Get(name string) Object {
-	v, ok := r.store[name]
-	if !ok && r.top != nil {
-		return r.top.Get(name)
-	}
-	//if v, ok_ := table[name]; ok_ {
-	//	return &BuiltFun{Fun_: v}
-	//}
+	v, ok := r.Lookup(name)
 	if !ok {
 		return NULL_
 	}
 	return v
 }
+
+// Lookup 沿作用域向上查找变量, 未声明时返回 false
+func (r *Env) Lookup(name string) (Object, bool) {
+	v, ok := r.store[name]
+	if !ok && r.top != nil {
+		return r.top.Lookup(name)
+	}
+	return v, ok
+}
 func (r *Env) Set(name string, object Object) {
 	r.store[name] = object
 }
+
+// Assign 修改已声明的变量, 沿作用域向上查找, 未声明时返回 false
+func (r *Env) Assign(name string, object Object) bool {
+	if _, ok := r.store[name]; ok {
+		r.store[name] = object
+		return true
+	}
+	if r.top != nil {
+		return r.top.Assign(name, object)
+	}
+	return false
+}
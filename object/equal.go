@@ -0,0 +1,62 @@
+package object
+
+// Equal == 的比较规则, VM 与解释器共用
+// int 与 float 按数值比较, 其余类型不同时不相等, array 与 hash 逐个比较元素, 函数与错误比较是否为同一个对象
+func Equal(left, right Object) bool {
+	return equal(left, right, map[[2]Object]bool{})
+}
+
+// equal seen 记录正在比较的容器, 包含自身的 array 与 hash 不会无限递归
+func equal(left, right Object, seen map[[2]Object]bool) bool {
+	if IsNumber(left) && IsNumber(right) {
+		if left.Type() == INT && right.Type() == INT {
+			return left.(*Integer).Value == right.(*Integer).Value
+		}
+		l, _ := ToFloat(left)
+		r, _ := ToFloat(right)
+		return l == r
+	}
+	if left.Type() != right.Type() {
+		return false
+	}
+	switch l := left.(type) {
+	case *Bool:
+		return l.Value == right.(*Bool).Value
+	case *Null:
+		return true
+	case *String:
+		return l.Value == right.(*String).Value
+	case *Array:
+		r := right.(*Array)
+		if l == r || seen[[2]Object{l, r}] {
+			return true
+		}
+		if len(l.Value) != len(r.Value) {
+			return false
+		}
+		seen[[2]Object{l, r}] = true
+		for i := range l.Value {
+			if !equal(l.Value[i], r.Value[i], seen) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		r := right.(*Hash)
+		if l == r || seen[[2]Object{l, r}] {
+			return true
+		}
+		if l.Len() != r.Len() {
+			return false
+		}
+		seen[[2]Object{l, r}] = true
+		for key, pair := range l.Pairs {
+			other, ok := r.Pairs[key]
+			if !ok || !equal(pair.Value, other.Value, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return left == right
+}
@@ -1,7 +1,39 @@
 package object
 
+import (
+	"fmt"
+	"hek/token"
+)
+
+// TraceFrame 调用栈中的一帧
+type TraceFrame struct {
+	Name string         //函数名
+	Ip   int            //出错或调用时的指令偏移, 解释器中没有指令, 为 -1
+	Pos  token.Position //指令对应的源码位置
+}
+
+func (t TraceFrame) String() string {
+	name := t.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	switch {
+	case t.Ip < 0 && !t.Pos.IsValid():
+		return name
+	case t.Ip < 0:
+		return fmt.Sprintf("%s (%s)", name, t.Pos)
+	case !t.Pos.IsValid():
+		return fmt.Sprintf("%s (ip %04d)", name, t.Ip)
+	}
+	return fmt.Sprintf("%s (%s, ip %04d)", name, t.Pos, t.Ip)
+}
+
+// Error 运行时错误, 可以被 catch 捕获, Trace 从出错的帧开始依次到 main
 type Error struct {
-	Msg string
+	Msg     string
+	Data    Object //error(msg, data) 附带的数据, 没有时为 nil
+	Trace   []TraceFrame
+	IsValue bool //被 catch 捕获或由 error() 创建, 作为普通的值使用, 不再向上抛出
 }
 
 func (e *Error) Type() ObjectType {
@@ -11,3 +43,49 @@ func (e *Error) Type() ObjectType {
 func (e *Error) Inspect() string {
 	return e.Msg
 }
+
+// Field 通过 e.message e.data e.cause e.trace 读取错误信息, cause 是 data 中包装的错误
+func (e *Error) Field(name string) (Object, bool) {
+	switch name {
+	case "message":
+		return &String{Value: e.Msg}, true
+	case "data":
+		if e.Data == nil {
+			return NULL_, true
+		}
+		return e.Data, true
+	case "cause":
+		if cause, ok := e.Data.(*Error); ok {
+			return cause, true
+		}
+		return NULL_, true
+	case "trace":
+		trace := make([]Object, len(e.Trace))
+		for i, frame := range e.Trace {
+			trace[i] = &String{Value: frame.String()}
+		}
+		return &Array{Value: trace}, true
+	}
+	return nil, false
+}
+
+// NewErrorFun error(msg) error(msg, data) 创建错误值, 可以作为返回值使用, 也可以 throw
+func NewErrorFun(ctx *Context, args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError(fmt.Sprintf("error 需要 1 或 2 个参数, 得到 %d 个", len(args)))
+	}
+	if err := checkArg("error", args, 0, STRING); err != nil {
+		return err
+	}
+	err := &Error{Msg: args[0].(*String).Value, IsValue: true}
+	if len(args) == 2 {
+		err.Data = args[1]
+	}
+	return err
+}
+func IsErrorFun(ctx *Context, args ...Object) Object {
+	if err := checkOne("is_error", args); err != nil {
+		return err
+	}
+	return boolObject(args[0].Type() == ERROR)
+}
@@ -1,12 +1,30 @@
 package object
 
 import (
+	"bytes"
 	"fmt"
 	"hek/ast"
 	"hek/token"
+	"math"
 )
 
+// Eval 解释执行, 执行时在当前帧记录节点的位置
+// 抛出的错误由最先返回它的节点记录调用栈, 与 VM 一样指向出错的位置
 func Eval(node ast.Node, envs *Env) Object {
+	ctx := envs.Context()
+	top := len(ctx.frames) - 1
+	prev := ctx.frames[top].Pos
+	if pos := node.Pos(); pos.IsValid() {
+		ctx.frames[top].Pos = pos
+	}
+	result := eval(node, envs)
+	if err, ok := result.(*Error); ok && !err.IsValue && err.Trace == nil {
+		err.Trace = ctx.trace()
+	}
+	ctx.frames[top].Pos = prev
+	return result
+}
+func eval(node ast.Node, envs *Env) Object {
 	switch n := node.(type) {
 	case *ast.Program:
 		return evalProgram(n.Statements, envs)
@@ -14,14 +32,28 @@ func Eval(node ast.Node, envs *Env) Object {
 		return Eval(n.Expression, envs)
 	case *ast.IntegerLiteral:
 		return &Integer{Value: n.Value}
+	case *ast.FloatLiteral:
+		return &Float{Value: n.Value}
 	case *ast.BoolExpression:
 		return boolObject(n.Value)
 	case *ast.PrefixExpression:
 		res := Eval(n.Right, envs)
+		if isError(res) {
+			return res
+		}
 		return evalPrefix(n.Token.Type, res)
 	case *ast.InfixExpression:
+		if n.Token.Type == token.AND || n.Token.Type == token.OR {
+			return evalLogical(n, envs)
+		}
 		left := Eval(n.Left, envs)
+		if isError(left) {
+			return left
+		}
 		right := Eval(n.Right, envs)
+		if isError(right) {
+			return right
+		}
 		return evalInfixExpression(n.Token.Type, left, right)
 	case *ast.IFExpression:
 		return evalIF(n, envs)
@@ -29,26 +61,52 @@ func Eval(node ast.Node, envs *Env) Object {
 		return evalStatement(n, envs)
 	case *ast.ReturnStatement:
 		return evalReturn(n, envs)
+	case *ast.TryStatement:
+		return evalTry(n, envs)
+	case *ast.ThrowStatement:
+		val := Eval(n.Value, envs)
+		if isError(val) {
+			return val
+		}
+		return ThrowValue(val)
 	case *ast.LetStatement:
 		res := Eval(n.Value, envs)
 		if isError(res) {
 			return res
 		}
+		//与编译器一样, 只有直接赋值的匿名函数以变量名命名
+		if f, ok := res.(*Fun); ok && f.Name == "" && isFunLiteral(n.Value) {
+			f.Name = n.Name.Value
+		}
 		envs.Set(n.Name.Value, res)
 	case *ast.Identifier:
-		return envs.Get(n.Value)
+		return evalIdentifier(n, envs)
 	case *ast.FunExpression:
 		return evalFun(n, envs)
 	case *ast.CallExpression:
 		return evalCall(n, envs)
 	case *ast.StringExpression:
 		return &String{Value: n.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolated(n, envs)
 	case *ast.ArrayExpression:
-		return evalArray(n)
+		return evalArray(n, envs)
 	case *ast.IndexExpression:
 		return evalIndex(n, envs)
+	case *ast.SliceExpression:
+		return evalSlice(n, envs)
 	case *ast.HashExpression:
-		return evalHash(n)
+		return evalHash(n, envs)
+	case *ast.AssigExpression:
+		return evalAssig(n, envs)
+	case *ast.SuffixExpression:
+		return evalSuffix(n, envs)
+	case *ast.ForExpression:
+		return evalFor(n, envs)
+	case *ast.BreakStatement:
+		return &Break{Label: labelName(n.Label), Pos: n.Pos()}
+	case *ast.ContinueStatement:
+		return &Continue{Label: labelName(n.Label), Pos: n.Pos()}
 	default:
 		return newError("未知语法")
 	}
@@ -58,10 +116,15 @@ func evalProgram(arr []ast.Statement, envs *Env) Object {
 	var result Object
 	for _, statement := range arr {
 		result = Eval(statement, envs)
-
+		if isError(result) {
+			return result
+		}
 		if v, ok := result.(*Return); ok {
 			return v.Value
 		}
+		if isLoopControl(result) {
+			return loopControlError(result)
+		}
 	}
 	return result
 }
@@ -75,95 +138,168 @@ func evalPrefix(types token.Type, object Object) Object {
 	switch types {
 	case token.BANG:
 		return evalPrefixBangExpression(object)
-	case token.MINUS:
-		return evalPrefixMinusExpression(object.(*Integer))
+	case token.MINUS, token.TILDE:
+		if f, ok := object.(*Float); ok && types == token.MINUS {
+			return &Float{Value: -f.Value}
+		}
+		val, ok := object.(*Integer)
+		if !ok {
+			return newError(fmt.Sprintf("%s 类型不支持该操作", object.Type().String()))
+		}
+		if types == token.TILDE {
+			return &Integer{Value: ^val.Value}
+		}
+		return evalPrefixMinusExpression(val)
 	}
 
 	return NULL_
 }
 func evalPrefixBangExpression(object Object) Object {
-	switch object {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL_:
-		return TRUE
-	default:
-		return FALSE
-	}
+	return boolObject(!Truthy(object))
 }
 func evalPrefixMinusExpression(val *Integer) Object {
 	return &Integer{Value: -val.Value}
 }
+
+// evalInfixExpression 类型检查的顺序与错误信息和 VM 保持一致
 func evalInfixExpression(types token.Type, left Object, right Object) Object {
-	if object := infixTypes(left, right); object.Type() == ERROR {
-		return object
+	switch types {
+	case token.EQ:
+		return boolObject(Equal(left, right))
+	case token.NotEq:
+		return boolObject(!Equal(left, right))
+	case token.LT, token.GT, token.LtEq, token.GtEq:
+		if !IsNumber(left) || !IsNumber(right) {
+			return newError("< > <= >= 运算 必须是数字类型")
+		}
 	}
-	if left.Type() == STRING {
-		if types == token.PLUS {
-			return &String{Value: fmt.Sprintf("%s%s", left.(*String).Value, right.(*String).Value)}
-		} else if types == token.EQ {
-			return boolObject(left.(*String).Value == right.(*String).Value)
-		} else if types == token.NotEq {
-			return boolObject(left.(*String).Value != right.(*String).Value)
+	if left.Type() == STRING && right.Type() == STRING {
+		if types != token.PLUS {
+			return newError("字符串类型只支持 '+' 的操作方式")
 		}
-		return newError("string 不支持该操作 " + types.ToString())
+		return &String{Value: fmt.Sprintf("%s%s", left.(*String).Value, right.(*String).Value)}
+	}
+	if left.Type() == INT && right.Type() == INT {
+		return evalIntegerInfix(types, left.(*Integer).Value, right.(*Integer).Value)
+	}
+	if IsNumber(left) && IsNumber(right) {
+		l, _ := ToFloat(left)
+		r, _ := ToFloat(right)
+		return evalFloatInfix(types, l, r)
 	}
+	return newError(fmt.Sprintf("操作类型不一致 %s - %s", left.Type().String(), right.Type().String()))
+}
+
+// evalFloatInfix int 与 float 混合运算时都提升为 float
+func evalFloatInfix(types token.Type, left, right float64) Object {
 	switch types {
 	case token.MINUS:
-		return &Integer{Value: left.(*Integer).Value - right.(*Integer).Value}
+		return &Float{Value: left - right}
 	case token.PLUS:
-		return &Integer{Value: left.(*Integer).Value + right.(*Integer).Value}
-	case token.SLASH:
-		return &Integer{Value: left.(*Integer).Value / right.(*Integer).Value}
+		return &Float{Value: left + right}
 	case token.ASTERISK:
-		return &Integer{Value: left.(*Integer).Value * right.(*Integer).Value}
+		return &Float{Value: left * right}
+	case token.SLASH, token.PERCENT:
+		if right == 0 {
+			return newError("除数不能为0")
+		}
+		if types == token.SLASH {
+			return &Float{Value: left / right}
+		}
+		return &Float{Value: math.Mod(left, right)}
 	case token.LT:
-		return boolObject(left.(*Integer).Value < right.(*Integer).Value)
+		return boolObject(left < right)
 	case token.GT:
-		return boolObject(left.(*Integer).Value > right.(*Integer).Value)
-	case token.EQ:
-		return boolObject(left.(*Integer).Value == right.(*Integer).Value)
-	case token.NotEq:
-		return boolObject(left.(*Integer).Value != right.(*Integer).Value)
+		return boolObject(left > right)
+	case token.LtEq:
+		return boolObject(left <= right)
+	case token.GtEq:
+		return boolObject(left >= right)
 	}
-	return NULL_
+	return newError("float 类型不支持位运算")
+}
+func evalIntegerInfix(types token.Type, left, right int64) Object {
+	switch types {
+	case token.MINUS:
+		return &Integer{Value: left - right}
+	case token.PLUS:
+		return &Integer{Value: left + right}
+	case token.SLASH, token.PERCENT:
+		if right == 0 {
+			return newError("除数不能为0")
+		}
+		if types == token.SLASH {
+			return &Integer{Value: left / right}
+		}
+		return &Integer{Value: left % right}
+	case token.ASTERISK:
+		return &Integer{Value: left * right}
+	case token.BitAnd:
+		return &Integer{Value: left & right}
+	case token.BitOr:
+		return &Integer{Value: left | right}
+	case token.BitXor:
+		return &Integer{Value: left ^ right}
+	case token.ShiftLeft, token.ShiftRight:
+		if right < 0 {
+			return newError("移位的位数不能为负数")
+		}
+		if types == token.ShiftLeft {
+			return &Integer{Value: left << right}
+		}
+		return &Integer{Value: left >> right}
+	case token.LT:
+		return boolObject(left < right)
+	case token.GT:
+		return boolObject(left > right)
+	case token.LtEq:
+		return boolObject(left <= right)
+	case token.GtEq:
+		return boolObject(left >= right)
+	}
+	return newError("int 不支持该操作 " + types.ToString())
+}
+
+// evalLogical 短路求值 && 与 ||, 结果总是 bool
+func evalLogical(n *ast.InfixExpression, envs *Env) Object {
+	left := Eval(n.Left, envs)
+	if isError(left) {
+		return left
+	}
+	if n.Token.Type == token.AND && !Truthy(left) {
+		return FALSE
+	}
+	if n.Token.Type == token.OR && Truthy(left) {
+		return TRUE
+	}
+	right := Eval(n.Right, envs)
+	if isError(right) {
+		return right
+	}
+	return boolObject(Truthy(right))
 }
 func evalIF(if_ *ast.IFExpression, envs *Env) Object {
 	condition := Eval(if_.Condition, envs)
 	if isError(condition) {
 		return condition
 	}
-	if isTrue(condition) {
+	if Truthy(condition) {
 		return Eval(if_.Consequence, envs)
 	} else {
 		if if_.Alternative != nil {
 			return Eval(if_.Alternative, envs)
 		}
 	}
-	return nil
-}
-func isTrue(object Object) bool {
-	switch object {
-	case TRUE:
-		return true
-	case FALSE:
-		return false
-	case NULL_:
-		return false
-	default:
-		return true
-	}
+	return NULL_
 }
 func evalStatement(stmt *ast.BlockStatement, envs *Env) Object {
-	var result Object
+	var result Object = NULL_
 	for _, statement := range stmt.Statements {
 		result = Eval(statement, envs)
 		if isError(result) {
 			return result
 		}
-		if result.Type() == RETURN {
+		if result.Type() == RETURN || isLoopControl(result) {
 			return result
 		}
 	}
@@ -171,70 +307,183 @@ func evalStatement(stmt *ast.BlockStatement, envs *Env) Object {
 }
 func evalReturn(ret *ast.ReturnStatement, envs *Env) Object {
 	object := &Return{Value: NULL_}
+	if ret.Value == nil {
+		return object
+	}
 	result := Eval(ret.Value, envs)
+	if isError(result) {
+		return result
+	}
 	object.Value = result
 	return object
 }
-func infixTypes(left Object, right Object) Object {
-	if left.Type() != right.Type() {
-		return newError(fmt.Sprintf("%s and %s type atypism", left.Type().String(), right.Type().String()))
-	}
-	return NULL_
-}
 func newError(msg string) *Error {
 	return &Error{Msg: msg}
 }
+
+// isError 判断是否是正在抛出的错误, catch 捕获的错误是普通的值
 func isError(object Object) bool {
-	return object.Type() == ERROR
+	err, ok := object.(*Error)
+	return ok && !err.IsValue
+}
+func isFunLiteral(expr ast.Expression) bool {
+	_, ok := expr.(*ast.FunExpression)
+	return ok
 }
 func evalFun(f *ast.FunExpression, envs *Env) Object {
 	funObject := &Fun{
-		Params: f.Params,
-		Block:  f.Block,
-		Env:    envs,
+		Params:   f.Params,
+		Defaults: f.Defaults,
+		Rest:     f.Rest,
+		Block:    f.Block,
+		Env:      envs,
 	}
 	if f.Name != nil {
+		funObject.Name = f.Name.Value
 		envs.Set(f.Name.Value, funObject)
 		return NULL_
 	}
 	return funObject
 }
+
+// evalIdentifier 变量优先于同名的内置函数, 都不存在时报错
+func evalIdentifier(n *ast.Identifier, envs *Env) Object {
+	if val, ok := envs.Lookup(n.Value); ok {
+		return val
+	}
+	if index, ok := GetNameIndex(n.Value); ok {
+		return GetFun(index)
+	}
+	return newError(fmt.Sprintf("%s: 使用了未定义的变量 %s", n.Pos(), n.Value))
+}
+
+// applyInternalFun 调用内置函数, 与 VM 一样要求内置函数必须有返回值
+func applyInternalFun(f *InternalFun, params []Object, ctx *Context) Object {
+	result := f.Fun_(ctx, params...)
+	if result == nil {
+		return newError("内置函数没有返回值")
+	}
+	return result
+}
+
+// callEvalFun 内置函数回调解释器中的函数
+func callEvalFun(fn Object, args ...Object) (Object, *Error) {
+	f, ok := fn.(*Fun)
+	if !ok {
+		return nil, newError("调用的不是一个方法")
+	}
+	result := applyFun(f, args, nil)
+	if isError(result) {
+		return nil, result.(*Error)
+	}
+	return result, nil
+}
 func evalCall(c *ast.CallExpression, envs *Env) Object {
 	fun := Eval(c.Fun, envs)
 	if isError(fun) {
 		return fun
 	}
-	params := evalCallParamExpression(c.Params, envs)
-	//if ifun, ok := fun.(*BuiltFun); ok {
-	//	return ifun.Fun_(params...)
-	//}
-	return applyFun(fun.(*Fun), params)
+	params, names, err := evalCallParamExpression(c.Params, envs)
+	if err != nil {
+		return err
+	}
+	if ifun, ok := fun.(*InternalFun); ok {
+		if len(names) > 0 {
+			return newError("内置函数不支持命名参数")
+		}
+		return applyInternalFun(ifun, params, envs.Context())
+	}
+	f, ok := fun.(*Fun)
+	if !ok {
+		return newError("调用的不是一个方法")
+	}
+	return applyFun(f, params, names)
 }
-func evalCallParamExpression(arr []ast.Expression, envs *Env) []Object {
+
+// evalCallParamExpression 计算调用参数, ...arr 展开为多个参数, 命名参数在最后, names 为它们的名字
+func evalCallParamExpression(arr []ast.Expression, envs *Env) ([]Object, []string, Object) {
 	var arr_ []Object
+	var names []string
 	for _, expression := range arr {
+		if named, ok := expression.(*ast.NamedArgument); ok {
+			names = append(names, named.Name.Value)
+			expression = named.Value
+		}
+		spread, isSpread := expression.(*ast.SpreadExpression)
+		if isSpread {
+			expression = spread.Value
+		}
 		result := Eval(expression, envs)
 		if isError(result) {
-			return []Object{result}
+			return nil, nil, result
+		}
+		if !isSpread {
+			arr_ = append(arr_, result)
+			continue
+		}
+		elements, ok := result.(*Array)
+		if !ok {
+			return nil, nil, newError(fmt.Sprintf("只能展开 array, 得到 %s", result.Type().String()))
 		}
-		arr_ = append(arr_, result)
+		arr_ = append(arr_, elements.Value...)
 	}
-	return arr_
+	return arr_, names, nil
 }
-func applyFun(f *Fun, params []Object) Object {
-	env := newFunEvn(params, f)
-	if len(params) != len(f.Params) {
-		return newError("参数数量不一致")
+func applyFun(f *Fun, params []Object, names []string) Object {
+	defaults := 0
+	for _, value := range f.Defaults {
+		if value != nil {
+			defaults++
+		}
+	}
+	paramNames := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		paramNames[i] = param.Value
+	}
+	params, bindErr := BindArgs(f.Name, paramNames, defaults, f.Rest != nil, params, names)
+	if bindErr != nil {
+		return bindErr
+	}
+	//与 VM 一样限制调用深度, 无限递归时抛出可以捕获的错误, 而不是耗尽 Go 的栈
+	ctx := f.Env.Context()
+	if len(ctx.frames) >= ctx.maxDepth {
+		return newError("stack overflow")
+	}
+	ctx.frames = append(ctx.frames, TraceFrame{Name: f.Name, Ip: -1})
+	defer func() { ctx.frames = ctx.frames[:len(ctx.frames)-1] }()
+	env, err := newFunEvn(params, f)
+	if err != nil {
+		return err
 	}
 	result := Eval(f.Block, env)
+	if isLoopControl(result) {
+		return loopControlError(result)
+	}
 	return unwrapRet(result)
 }
-func newFunEvn(params []Object, fp *Fun) *Env {
+
+// newFunEvn 绑定参数, 没有传入的参数在函数环境中计算默认值
+func newFunEvn(params []Object, fp *Fun) (*Env, Object) {
 	e := NewEnv(fp.Env)
 	for index, name := range fp.Params {
-		e.Set(name.Value, params[index])
+		if index < len(params) && params[index] != nil {
+			e.Set(name.Value, params[index])
+			continue
+		}
+		value := Eval(fp.Defaults[index], e)
+		if isError(value) {
+			return nil, value
+		}
+		e.Set(name.Value, value)
 	}
-	return e
+	if fp.Rest != nil {
+		rest := []Object{}
+		if len(params) > len(fp.Params) {
+			rest = append(rest, params[len(fp.Params):]...)
+		}
+		e.Set(fp.Rest.Value, &Array{Value: rest})
+	}
+	return e, nil
 }
 func unwrapRet(object Object) Object {
 	if v, ok := object.(*Return); ok {
@@ -242,51 +491,189 @@ func unwrapRet(object Object) Object {
 	}
 	return object
 }
-func evalArray(array *ast.ArrayExpression) Object {
+func evalInterpolated(s *ast.InterpolatedString, envs *Env) Object {
+	var out bytes.Buffer
+	for _, part := range s.Parts {
+		val := Eval(part, envs)
+		if isError(val) {
+			return val
+		}
+		out.WriteString(val.Inspect())
+	}
+	return &String{Value: out.String()}
+}
+func evalArray(array *ast.ArrayExpression, envs *Env) Object {
 	object := &Array{}
 	for _, expression := range array.Value {
-		object.Value = append(object.Value, Eval(expression, nil))
+		element := Eval(expression, envs)
+		if isError(element) {
+			return element
+		}
+		object.Value = append(object.Value, element)
 	}
 	return object
 }
 func evalIndex(index *ast.IndexExpression, envs *Env) Object {
-	arr := Eval(index.Left, envs)
-
-	if arr.Type() == NULL {
-		return NULL_
+	left := Eval(index.Left, envs)
+	if isError(left) {
+		return left
+	}
+	i := Eval(index.Index, envs)
+	if isError(i) {
+		return i
+	}
+	return Index(left, i)
+}
+func evalSlice(slice *ast.SliceExpression, envs *Env) Object {
+	left := Eval(slice.Left, envs)
+	if isError(left) {
+		return left
 	}
-	if array, ok := arr.(*Array); ok {
-		i := Eval(index.Index, envs)
-		if i == NULL_ || isError(i) {
-			return i
+	bounds := []Object{NULL_, NULL_}
+	for i, bound := range []ast.Expression{slice.Start, slice.End} {
+		if bound == nil {
+			continue
 		}
-		index_ := i.(*Integer).Value
-		if int(index_) >= len(array.Value) {
-			return NULL_
+		bounds[i] = Eval(bound, envs)
+		if isError(bounds[i]) {
+			return bounds[i]
 		}
-		return array.Value[index_]
-	} else if hash, ok := arr.(*Hash); ok {
-		i := Eval(index.Index, envs)
-		val, ok := hash.Value[i]
-		if !ok {
+	}
+	return Slice(left, bounds[0], bounds[1])
+}
+func evalHash(hash *ast.HashExpression, envs *Env) Object {
+	h := NewHash()
+	for _, pair := range hash.Pairs {
+		key := Eval(pair.Key, envs)
+		if isError(key) {
+			return key
+		}
+		value := Eval(pair.Value, envs)
+		if isError(value) {
+			return value
+		}
+		if err := h.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return h
+}
+func labelName(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value
+}
+func isLoopControl(object Object) bool {
+	return object != nil && (object.Type() == BREAK || object.Type() == CONTINUE)
+}
+func evalFor(f *ast.ForExpression, envs *Env) Object {
+	env := NewEnv(envs)
+	label := labelName(f.Label)
+	if res := Eval(f.Left, env); isError(res) {
+		return res
+	}
+	for {
+		condition := Eval(f.Mid, env)
+		if isError(condition) {
+			return condition
+		}
+		if !Truthy(condition) {
+			break
+		}
+		result := Eval(f.Block, env)
+		if isError(result) {
+			return result
+		}
+		switch r := result.(type) {
+		case *Return:
+			return r
+		case *Break:
+			if r.Label != "" && r.Label != label {
+				return r
+			}
 			return NULL_
+		case *Continue:
+			if r.Label != "" && r.Label != label {
+				return r
+			}
+		}
+		if res := Eval(f.Right, env); isError(res) {
+			return res
 		}
+	}
+	return NULL_
+}
+func evalAssig(a *ast.AssigExpression, envs *Env) Object {
+	val := Eval(a.Value, envs)
+	if isError(val) {
 		return val
 	}
+	switch name := a.Name.(type) {
+	case *ast.Identifier:
+		if !envs.Assign(name.Value, val) {
+			return newError(fmt.Sprintf("%s: 不能对一个没有声明的变量赋值 %s", name.Pos(), name.Value))
+		}
+	case *ast.IndexExpression:
+		return evalIndexAssig(name, val, envs)
+	}
+	return NULL_
+}
+func evalIndexAssig(index *ast.IndexExpression, val Object, envs *Env) Object {
+	left := Eval(index.Left, envs)
+	if isError(left) {
+		return left
+	}
+	key := Eval(index.Index, envs)
+	if isError(key) {
+		return key
+	}
+	if err := SetIndex(left, key, val); err != nil {
+		return err
+	}
+	return NULL_
+}
+func evalSuffix(s *ast.SuffixExpression, envs *Env) Object {
+	val := envs.Get(s.Left.Value)
+	if !IsNumber(val) {
+		return newError(s.Token.Literal + " 只能用于数字类型")
+	}
+	op := token.PLUS
+	if s.Token.Type == token.TwoMinus {
+		op = token.MINUS
+	}
+	envs.Assign(s.Left.Value, evalInfixExpression(op, val, &Integer{Value: 1}))
 	return NULL_
 }
-func evalHash(hash *ast.HashExpression) Object {
-	h := &Hash{map[Object]Object{}}
-	for index, val := range hash.Value {
-		key := Eval(index, nil)
-		if key == NULL_ {
-			return newError("hash key err")
+
+// ThrowValue 将 throw 的值转换为错误, 解释器与 VM 共用
+// 其他类型的值以 Inspect 作为错误信息, 原来的值可以通过 e.data 取得
+func ThrowValue(val Object) *Error {
+	if err, ok := val.(*Error); ok {
+		return &Error{Msg: err.Msg, Data: err.Data, Trace: err.Trace}
+	}
+	return &Error{Msg: val.Inspect(), Data: val}
+}
+
+// evalTry 捕获 try 中抛出的错误, finally 中的 return break continue 与错误会覆盖之前的结果
+func evalTry(t *ast.TryStatement, envs *Env) Object {
+	result := Eval(t.Block, envs)
+	if isError(result) && t.Catch != nil {
+		err := result.(*Error)
+		err.IsValue = true
+		if t.Param != nil {
+			envs.Set(t.Param.Value, err)
 		}
-		value := Eval(val, nil)
-		if key == NULL_ {
-			return newError("hash value err")
+		result = Eval(t.Catch, envs)
+	}
+	if t.Finally != nil {
+		final := Eval(t.Finally, envs)
+		if isError(final) || final != nil && final.Type() == RETURN || isLoopControl(final) {
+			return final
 		}
-		h.Value[key] = value
 	}
-	return h
+	if isError(result) || result != nil && result.Type() == RETURN || isLoopControl(result) {
+		return result
+	}
+	return NULL_
 }
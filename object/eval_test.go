@@ -1,23 +1,348 @@
 package object
 
 import (
-	"fmt"
+	"bytes"
 	"hek/lexer"
 	"hek/parser"
+	"strconv"
 	"testing"
 )
 
 func TestEval(t *testing.T) {
-	input := "let a = fun (x) { return x + 5;}(5)"
+	input := "let a = fun (x) { return x + 5;}(5); a"
+	if got := runEval(t, input); got != "10" {
+		t.Errorf("%q = %s, want 10", input, got)
+	}
+}
+
+// runEval 解释执行 input, 返回结果的 Inspect, 出错时就是错误信息
+func runEval(t *testing.T, input string) string {
+	t.Helper()
+	p := parser.NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse %q: %v", input, p.Errors())
+	}
+	return Eval(program, NewEnv(nil)).Inspect()
+}
 
-	l := lexer.NewLexer(input)
+func TestEvalHash(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"{\"a\": 1}[\"a\"]", "1"},
+		{"let k = 2; {k: \"two\", true: 1}[2]", "two"},
+		{"{\"b\": 1, \"a\": 2}", "{b:1,a:2}"},
+		{"{\"a\": 1}[\"b\"]", "null"},
+		{"{[1]: 1}", "array 类型不能作为 hash 的键"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
 
-	p := parser.NewParser(l)
+func TestEvalLoop(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"let s = 0; for (let i = 0; i < 10; i++) { if (i == 3) { continue; } if (i == 6) { break; } s = s + i; } s", "12"},
+		{"let c = 0; outer: for (let i = 0; i < 5; i++) { for (let j = 0; j < 5; j++) { if (j == 2) { continue outer; } if (i == 3) { break outer; } c = c + 1; } } c", "6"},
+		{"fun f() { let n = 0; for (let i = 10; i > 0; i--) { if (i < 5) { return n; } n++; } } f()", "6"},
+		{`let n = 0; for (let i = 0; i < 3; i++) { n++; error("x") } n`, "3"},
+		{"fun f() { continue; } f()", "1:11: continue 只能在循环中使用"},
+		{"break;", "1:1: break 只能在循环中使用"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
 
-	program := p.ParseProgram()
+func TestEvalLogical(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"true && false", "false"},
+		{"false || true", "true"},
+		{"1 < 2 && 2 < 3 || false", "true"},
+		{"let n = 0; let inc = fun() { n = n + 1; return true; }; false && inc(); true || inc(); n", "0"},
+		{"let n = 0; let inc = fun() { n = n + 1; return true; }; true && inc(); false || inc(); n", "2"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalOperators(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"3 <= 3", "true"},
+		{"3 >= 4", "false"},
+		{"-7 % 3", "-1"},
+		{"6 & 3 | 8 ^ 1", "11"},
+		{"1 + 2 << 1", "6"},
+		{"~5", "-6"},
+		{"true == true", "true"},
+		{"1 / 0", "除数不能为0"},
+		{"5 % 0", "除数不能为0"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalFloat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{".5 + 1", "1.5"},
+		{"1e-3", "0.001"},
+		{"1 / 2.0", "0.5"},
+		{"7.5 % 2", "1.5"},
+		{"-2.5", "-2.5"},
+		{"1.0 == 1", "true"},
+		{"2 > 1.5", "true"},
+		{"let x = 0.5; x++; x", "1.5"},
+		{"1.5 / 0", "除数不能为0"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let name = "hek"; "hello ${name}!"`, "hello hek!"},
+		{`"${1 + 2}${3}"`, "33"},
+		{`"a ${ {"k": "${1.5}"}["k"] } b"`, "a 1.5 b"},
+		{`"${[1, true]}"`, "[1,true]"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalIndexAndSlice(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"你好"[1]`, "好"},
+		{`[1, 2, 3][-1]`, "null"},
+		{`[1, 2, 3, 4][1:3]`, "[2,3]"},
+		{`[1, 2, 3, 4][-2:]`, "[3,4]"},
+		{`"hello"[:-1]`, "hell"},
+		{`"你好世界"[1:3]`, "好世"},
+		{`[1][true:]`, "切片的下标只能int类型"},
+		{`1[0]`, "int 类型不支持索引操作"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
 
+func TestEvalFunParams(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let sub = fun(a, b) { return a - b }; sub(5, 3)`, "2"},
+		{`let f = fun(a, b = a + 1) { return [a, b] }; f(3)`, "[3,4]"},
+		{`let f = fun(a, ...rest) { return [a, rest] }; f(1, 2, 3)`, "[1,[2,3]]"},
+		{`let f = fun(...xs) { return xs }; let arr = [2, 3]; f(1, ...arr, 4)`, "[1,2,3,4]"},
+		{`let add = fun(a, b) { return a + b }; add(1)`, "add 需要 2 个参数, 得到 1 个"},
+		{`fun f(a, b = 1) { return a }; f()`, "f 需要 1 到 2 个参数, 得到 0 个"},
+		{`let f = fun(a) { return a }; f(...1)`, "只能展开 array, 得到 int"},
+		{`1()`, "调用的不是一个方法"},
+		{`let x = 1; let f = fun() { let x = x + 1; return x }; [f(), x]`, "[2,1]"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalTryCatch(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let r = 0; try { throw "boom" } catch (e) { r = e["message"] }; r`, "boom"},
+		{`let r = 0; try { 1 + true } catch (e) { r = 1 }; r`, "1"},
+		{`let f = fun() { try { return 1 } finally { 2 } }; f()`, "1"},
+		{`let r = 0; try { try { throw "x" } finally { r = 1 } } catch (e) { r = r + 1 }; r`, "2"},
+		{`let f = fun() { throw "deep" }; let r = 0; try { f() } catch (e) { r = e }; r`, "deep"},
+		{`throw "uncaught"; 1`, "uncaught"},
+		{`let r = 0; try { [1, 1 / 0] } catch (e) { r = e.message }; r`, "除数不能为0"},
+		{`[1, error("v")][1].message`, "v"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrorValues(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let r = 0; try { throw "x" } catch (e) { r = e.message }; r`, "x"},
+		{`let h = {"a": {"b": 2}}; h.a.b`, "2"},
+		{`let r = 0; try { throw "x" } catch (e) { r = e }; r.cause`, "null"},
+		{`let r = 0; try { throw [1, {"k": 2}] } catch (e) { r = [e.message, e.data[1].k] }; r`, "[[1,{k:2}],2]"},
+		{`let r = 0; try { throw 1 } catch (e) { r = e.data + 1 }; r`, "2"},
+		{`let r = 0; try { fun() { 1 / 0 }() } catch (e) { r = e.trace }; r`, "[<anonymous> (1:28),main (1:33)]"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalEquality(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"1" == 1`, "false"},
+		{`1 == 1.0`, "true"},
+		{`[1, [2, "a"]] == [1, [2, "a"]]`, "true"},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, "true"},
+		{`{"a": 1} != {"a": 2}`, "true"},
+		{`fun() {} == fun() {}`, "false"},
+		{`let f = fun() {}; f == f`, "true"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalTruthiness(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`if (1) { "t" } else { "f" }`, "t"},
+		{`if (0) { "t" } else { "f" }`, "f"},
+		{`if ("") { "t" } else { "f" }`, "f"},
+		{`if ([]) { "t" } else { "f" }`, "f"},
+		{`if ({}) { "t" } else { "f" }`, "f"},
+		{`[!0, !1, !"", !"a", ![], ![1], !{}]`, "[true,false,true,false,true,false,true]"},
+		{`[1 && "a", 0 || [], [] || {"a": 1}, "" && 1]`, "[true,false,true,false]"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalOperatorErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"a" + 1`, "操作类型不一致 string - int"},
+		{`1 < "a"`, "< > <= >= 运算 必须是数字类型"},
+		{`"a" - "b"`, "字符串类型只支持 '+' 的操作方式"},
+		{`-"a"`, "string 类型不支持该操作"},
+		{`1.5 & 1`, "float 类型不支持位运算"},
+		{`fun g() { } g()`, "null"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`len("你好")`, "2"},
+		{`map([1, 2], fun(x) { x * 2 })`, "[2,4]"},
+		{`reduce([1, 2, 3], fun(a, b) { a + b }, 0)`, "6"},
+		{`sort_by(["ccc", "a"], len)`, "[a,ccc]"},
+		{`let len = fun(x) { 0 }; len("abc")`, "0"},
+		{`type(len)`, "builtin"},
+		{`map([1], fun(x) { throw "bad" })`, "bad"},
+		{`missing`, "1:1: 使用了未定义的变量 missing"},
+		{`y = 1`, "1:1: 不能对一个没有声明的变量赋值 y"},
+	}
+	for _, tt := range tests {
+		if got := runEval(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	var out, other bytes.Buffer
 	env := NewEnv(nil)
-	result := Eval(program, env)
+	env.Context().Out = &out
+	env.Context().SetArgs([]string{"x", "y"})
+	otherEnv := NewEnv(nil)
+	otherEnv.Context().Out = &other
+
+	program := parser.NewParser(lexer.NewLexer(`echo("a"); println(1, [2]); echo(); fun f() { echo(args()) } f()`)).ParseProgram()
+	Eval(program, env)
+	Eval(parser.NewParser(lexer.NewLexer(`echo(args())`)).ParseProgram(), otherEnv)
+	if got, want := out.String(), "a\n1[2]\n[x,y]\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if got, want := other.String(), "[]\n"; got != want {
+		t.Errorf("other output = %q, want %q", got, want)
+	}
+}
 
-	fmt.Println(result)
+func TestHashStringKeys(t *testing.T) {
+	a, b := &String{Value: "key"}, &String{Value: "key"}
+	if a.HashKey() != b.HashKey() {
+		t.Errorf("equal strings have different keys")
+	}
+	h := NewHash()
+	for i := 0; i < 5000; i++ {
+		if err := h.Set(&String{Value: strconv.Itoa(i)}, &Integer{Value: int64(i)}); err != nil {
+			t.Fatal(err.Msg)
+		}
+	}
+	if h.Len() != 5000 {
+		t.Fatalf("len = %d, want 5000", h.Len())
+	}
+	for i := 0; i < 5000; i++ {
+		val, _ := h.Get(&String{Value: strconv.Itoa(i)})
+		if val.Inspect() != strconv.Itoa(i) {
+			t.Fatalf("h[%q] = %s", strconv.Itoa(i), val.Inspect())
+		}
+	}
 }
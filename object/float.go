@@ -0,0 +1,47 @@
+package object
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT
+}
+
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(str, ".eIN") {
+		return str
+	}
+	return str + ".0"
+}
+
+// HashKey 与 Equal 一致, 整数值的 float 与对应的 int 是同一个键, -0.0 与 0 也是
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return HashKey{Type: INT, Value: uint64(int64(f.Value))}
+	}
+	return HashKey{Type: FLOAT, Value: math.Float64bits(f.Value)}
+}
+
+// IsNumber int 与 float 都属于数字
+func IsNumber(obj Object) bool {
+	return obj.Type() == INT || obj.Type() == FLOAT
+}
+
+// ToFloat 将数字转换为 float64, 用于 int 与 float 混合运算
+func ToFloat(obj Object) (float64, bool) {
+	switch n := obj.(type) {
+	case *Integer:
+		return float64(n.Value), true
+	case *Float:
+		return n.Value, true
+	}
+	return 0, false
+}
@@ -1,14 +1,18 @@
 package object
 
 import (
+	"fmt"
 	"hek/ast"
 	"hek/code"
 )
 
 type Fun struct {
-	Params []*ast.Identifier
-	Block  *ast.BlockStatement
-	Env    *Env
+	Params   []*ast.Identifier
+	Defaults []ast.Expression
+	Rest     *ast.Identifier
+	Block    *ast.BlockStatement
+	Env      *Env
+	Name     string //函数名, 匿名函数为空
 }
 
 func (f *Fun) Type() ObjectType {
@@ -16,13 +20,39 @@ func (f *Fun) Type() ObjectType {
 }
 
 func (f Fun) Inspect() string {
-	return "fun"
+	return funInspect(f.Name)
+}
+
+// funInspect 解释器与 VM 的函数显示相同, 匿名函数只显示 fun
+func funInspect(name string) string {
+	if name == "" {
+		return "fun"
+	}
+	return "fun " + name
+}
+
+// Cell 被闭包捕获的变量, 外层函数与闭包共享同一个 Cell, 赋值对双方都可见
+type Cell struct {
+	Value Object
+}
+
+// FreeVar 闭包捕获的变量在外层函数中的位置, Local 为 false 时是外层函数自己捕获的变量
+type FreeVar struct {
+	Local bool
+	Index int
 }
 
 type CompliedFun struct {
 	Instructions code.Instructions
-	Free         []Object
+	Free         []*Cell
+	FreeVars     []FreeVar //编译期记录, 创建闭包时按此捕获
 	NumLocal     int
+	NumParams    int            //不含可变参数
+	ParamNames   []string       //参数名, 按名字传参时使用, 不含可变参数
+	NumDefaults  int            //有默认值的参数数量, 都在参数列表末尾
+	Variadic     bool           //最后一个局部变量是 ...rest
+	Name         string         //函数名, 匿名函数为空
+	Lines        code.LineTable //指令偏移对应的源码位置
 }
 
 func (c *CompliedFun) Type() ObjectType {
@@ -30,5 +60,62 @@ func (c *CompliedFun) Type() ObjectType {
 }
 
 func (c *CompliedFun) Inspect() string {
-	return "Complied fun"
+	return funInspect(c.Name)
+}
+
+// CheckArity 检查调用时的参数数量, 解释器与 VM 共用
+func CheckArity(name string, params, defaults int, variadic bool, argc int) *Error {
+	required := params - defaults
+	if argc >= required && (variadic || argc <= params) {
+		return nil
+	}
+	if name == "" {
+		name = "匿名函数"
+	}
+	if variadic {
+		return newError(fmt.Sprintf("%s 至少需要 %d 个参数, 得到 %d 个", name, required, argc))
+	}
+	if defaults > 0 {
+		return newError(fmt.Sprintf("%s 需要 %d 到 %d 个参数, 得到 %d 个", name, required, params, argc))
+	}
+	return newError(fmt.Sprintf("%s 需要 %d 个参数, 得到 %d 个", name, params, argc))
+}
+
+// BindArgs 按参数名把命名参数放到对应的位置, names 是 args 末尾命名参数的名字
+// 返回的参数中没有传入的为 nil, 由默认值补上, 解释器与 VM 共用
+func BindArgs(name string, params []string, defaults int, variadic bool, args []Object, names []string) ([]Object, *Error) {
+	if err := CheckArity(name, len(params), defaults, variadic, len(args)); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return args, nil
+	}
+	if name == "" {
+		name = "匿名函数"
+	}
+	positional := len(args) - len(names)
+	bound := make([]Object, len(params))
+	copy(bound, args[:positional])
+	for i, argName := range names {
+		index := -1
+		for j, param := range params {
+			if param == argName {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			return nil, newError(fmt.Sprintf("%s 没有名为 %s 的参数", name, argName))
+		}
+		if bound[index] != nil {
+			return nil, newError(fmt.Sprintf("%s 的参数 %s 重复传入", name, argName))
+		}
+		bound[index] = args[positional+i]
+	}
+	for i := 0; i < len(params)-defaults; i++ {
+		if bound[i] == nil {
+			return nil, newError(fmt.Sprintf("%s 缺少参数 %s", name, params[i]))
+		}
+	}
+	return bound, nil
 }
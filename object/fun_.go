@@ -3,61 +3,65 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"unicode/utf8"
 )
 
-func Len(args ...Object) Object {
-	if len(args) <= 0 {
-		return &Error{Msg: "len param < 1"}
+func Len(ctx *Context, args ...Object) Object {
+	if err := checkOne("len", args); err != nil {
+		return err
 	}
 
 	switch arg := args[0].(type) {
 	case *String:
-		return &Integer{Value: int64(len(arg.Value))}
+		return &Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 	case *Array:
 		return &Integer{Value: int64(len(arg.Value))}
+	case *Hash:
+		return &Integer{Value: int64(arg.Len())}
 	default:
-		return nil
+		return newError(fmt.Sprintf("len 第 1 个参数必须是 %s, 得到 %s", joinTypes(STRING, ARRAY, HASH), arg.Type().String()))
 	}
 }
-func Put(args ...Object) Object {
-	if len(args) < 2 {
-		return nil
+func Put(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError(fmt.Sprintf("put 需要 2 个参数, 得到 %d 个", len(args)))
 	}
-	var arr *Array
-	if v, ok := args[0].(*Array); !ok {
-		return nil
-	} else {
-		arr = v
+	if err := checkArg("put", args, 0, ARRAY); err != nil {
+		return err
 	}
+	arr := args[0].(*Array)
 	arr.Value = append(arr.Value, args[1])
 	return arr
 }
-func Println(args ...Object) Object {
-	var out bytes.Buffer
+func Println(ctx *Context, args ...Object) Object {
+	var buf bytes.Buffer
 	for _, val := range args {
-		out.WriteString(val.Inspect())
+		buf.WriteString(val.Inspect())
 	}
-	fmt.Println(out.String())
+	_, _ = fmt.Fprintln(ctx.Out, buf.String())
 	return NULL_
 }
-func Echo(args ...Object) Object {
+func Echo(ctx *Context, args ...Object) Object {
 	if len(args) < 1 {
 		return NULL_
 	}
-	fmt.Println(args[0].Inspect())
+	_, _ = fmt.Fprintln(ctx.Out, args[0].Inspect())
 	return NULL_
 }
-func StringReversal(args ...Object) Object {
-	if args[0].Type() != STRING {
-		return nil
+func StringReversal(ctx *Context, args ...Object) Object {
+	if err := checkArgs("str_rev", args, STRING); err != nil {
+		return err
 	}
 
-	var out bytes.Buffer
-	v := args[0].(*String)
-	le := len(v.Value)
-	for i := 0; i < le; i++ {
-		out.WriteByte(v.Value[le-i-1])
+	runes := []rune(args[0].(*String).Value)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
 	}
+	return &String{Value: string(runes)}
+}
 
-	return &String{Value: out.String()}
+func Args(ctx *Context, args ...Object) Object {
+	arr := make([]Object, len(ctx.Args))
+	copy(arr, ctx.Args)
+	return &Array{Value: arr}
 }
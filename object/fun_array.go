@@ -0,0 +1,378 @@
+package object
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func isCallable(obj Object) bool {
+	switch obj.Type() {
+	case FUN, CompiledFun, BUILTFun:
+		return true
+	}
+	return false
+}
+
+// callFun 调用 hek 函数, 执行引擎的错误会原样返回
+func callFun(ctx *Context, fn Object, args ...Object) (Object, *Error) {
+	if f, ok := fn.(*InternalFun); ok {
+		obj := f.Fun_(ctx, args...)
+		if err, ok := obj.(*Error); ok && !err.IsValue {
+			return nil, err
+		}
+		return obj, nil
+	}
+	if ctx == nil || ctx.Call == nil {
+		return nil, newError("当前环境不支持回调函数")
+	}
+	return ctx.Call(fn, args...)
+}
+func checkCallable(name string, args []Object, i int) *Error {
+	if !isCallable(args[i]) {
+		return newError(fmt.Sprintf("%s 第 %d 个参数必须是函数, 得到 %s", name, i+1, args[i].Type().String()))
+	}
+	return nil
+}
+
+func arrayIndex(arr *Array, val Object) int {
+	for i, element := range arr.Value {
+		if Equal(element, val) {
+			return i
+		}
+	}
+	return -1
+}
+func First(ctx *Context, args ...Object) Object {
+	if err := checkArgs("first", args, ARRAY); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	if len(arr) == 0 {
+		return NULL_
+	}
+	return arr[0]
+}
+func Last(ctx *Context, args ...Object) Object {
+	if err := checkArgs("last", args, ARRAY); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	if len(arr) == 0 {
+		return NULL_
+	}
+	return arr[len(arr)-1]
+}
+
+// Rest 返回除第一个元素以外的新数组, 空数组返回 null
+func Rest(ctx *Context, args ...Object) Object {
+	if err := checkArgs("rest", args, ARRAY); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	if len(arr) == 0 {
+		return NULL_
+	}
+	rest := make([]Object, len(arr)-1)
+	copy(rest, arr[1:])
+	return &Array{Value: rest}
+}
+
+// Push 将一个或多个值追加到数组末尾, 返回数组本身
+func Push(ctx *Context, args ...Object) Object {
+	if len(args) < 2 {
+		return newError(fmt.Sprintf("push 至少需要 2 个参数, 得到 %d 个", len(args)))
+	}
+	if err := checkArg("push", args, 0, ARRAY); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	arr.Value = append(arr.Value, args[1:]...)
+	return arr
+}
+
+// Pop 删除并返回数组的最后一个元素, 空数组返回 null
+func Pop(ctx *Context, args ...Object) Object {
+	if err := checkArgs("pop", args, ARRAY); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	if len(arr.Value) == 0 {
+		return NULL_
+	}
+	last := arr.Value[len(arr.Value)-1]
+	arr.Value = arr.Value[:len(arr.Value)-1]
+	return last
+}
+
+// Insert insert(arr, i, val) 在下标 i 处插入 val, 返回数组本身
+func Insert(ctx *Context, args ...Object) Object {
+	if len(args) != 3 {
+		return newError(fmt.Sprintf("insert 需要 3 个参数, 得到 %d 个", len(args)))
+	}
+	if err := checkArg("insert", args, 0, ARRAY); err != nil {
+		return err
+	}
+	if err := checkArg("insert", args, 1, INT); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	index := args[1].(*Integer).Value
+	if index < 0 || index > int64(len(arr.Value)) {
+		return newError(fmt.Sprintf("insert 的下标 %d 超出范围", index))
+	}
+	arr.Value = append(arr.Value, nil)
+	copy(arr.Value[index+1:], arr.Value[index:])
+	arr.Value[index] = args[2]
+	return arr
+}
+
+// Remove remove(arr, i) 删除并返回下标 i 处的元素
+func Remove(ctx *Context, args ...Object) Object {
+	if err := checkArgs("remove", args, ARRAY, INT); err != nil {
+		return err
+	}
+	arr := args[0].(*Array)
+	index := args[1].(*Integer).Value
+	if index < 0 || index >= int64(len(arr.Value)) {
+		return newError(fmt.Sprintf("remove 的下标 %d 超出范围", index))
+	}
+	val := arr.Value[index]
+	arr.Value = append(arr.Value[:index], arr.Value[index+1:]...)
+	return val
+}
+func Reverse(ctx *Context, args ...Object) Object {
+	if err := checkArgs("reverse", args, ARRAY); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	reversed := make([]Object, len(arr))
+	for i, element := range arr {
+		reversed[len(arr)-1-i] = element
+	}
+	return &Array{Value: reversed}
+}
+
+// Concat 将多个数组拼接为新数组
+func Concat(ctx *Context, args ...Object) Object {
+	var arr []Object
+	for i := range args {
+		if err := checkArg("concat", args, i, ARRAY); err != nil {
+			return err
+		}
+		arr = append(arr, args[i].(*Array).Value...)
+	}
+	if arr == nil {
+		arr = []Object{}
+	}
+	return &Array{Value: arr}
+}
+
+// compareKey 比较排序用的两个键, 只能都是数字或都是字符串
+func compareKey(name string, left, right Object) (bool, *Error) {
+	if IsNumber(left) && IsNumber(right) {
+		l, _ := ToFloat(left)
+		r, _ := ToFloat(right)
+		return l < r, nil
+	}
+	if left.Type() == STRING && right.Type() == STRING {
+		return left.(*String).Value < right.(*String).Value, nil
+	}
+	return false, newError(fmt.Sprintf("%s 无法比较 %s 与 %s", name, left.Type().String(), right.Type().String()))
+}
+
+// sortArray 按 keys 对 arr 稳定排序, 返回新数组
+func sortArray(name string, arr, keys []Object) Object {
+	index := make([]int, len(arr))
+	for i := range index {
+		index[i] = i
+	}
+	var err *Error
+	sort.SliceStable(index, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var less bool
+		less, err = compareKey(name, keys[index[i]], keys[index[j]])
+		return less
+	})
+	if err != nil {
+		return err
+	}
+	sorted := make([]Object, len(arr))
+	for i, j := range index {
+		sorted[i] = arr[j]
+	}
+	return &Array{Value: sorted}
+}
+
+// Sort 返回排序后的新数组, 元素必须都是数字或都是字符串
+func Sort(ctx *Context, args ...Object) Object {
+	if err := checkArgs("sort", args, ARRAY); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	return sortArray("sort", arr, arr)
+}
+
+// SortBy sort_by(arr, fn) 按 fn(元素) 的结果排序
+func SortBy(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError(fmt.Sprintf("sort_by 需要 2 个参数, 得到 %d 个", len(args)))
+	}
+	if err := checkArg("sort_by", args, 0, ARRAY); err != nil {
+		return err
+	}
+	if err := checkCallable("sort_by", args, 1); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	keys := make([]Object, len(arr))
+	for i, element := range arr {
+		key, err := callFun(ctx, args[1], element)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+	return sortArray("sort_by", arr, keys)
+}
+func Map(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError(fmt.Sprintf("map 需要 2 个参数, 得到 %d 个", len(args)))
+	}
+	if err := checkArg("map", args, 0, ARRAY); err != nil {
+		return err
+	}
+	if err := checkCallable("map", args, 1); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	result := make([]Object, len(arr))
+	for i, element := range arr {
+		val, err := callFun(ctx, args[1], element)
+		if err != nil {
+			return err
+		}
+		result[i] = val
+	}
+	return &Array{Value: result}
+}
+
+// Filter 保留 fn(元素) 为真的元素
+func Filter(ctx *Context, args ...Object) Object {
+	if len(args) != 2 {
+		return newError(fmt.Sprintf("filter 需要 2 个参数, 得到 %d 个", len(args)))
+	}
+	if err := checkArg("filter", args, 0, ARRAY); err != nil {
+		return err
+	}
+	if err := checkCallable("filter", args, 1); err != nil {
+		return err
+	}
+	result := []Object{}
+	for _, element := range args[0].(*Array).Value {
+		val, err := callFun(ctx, args[1], element)
+		if err != nil {
+			return err
+		}
+		if Truthy(val) {
+			result = append(result, element)
+		}
+	}
+	return &Array{Value: result}
+}
+
+// Reduce reduce(arr, fn, init) 依次计算 fn(acc, 元素), 省略 init 时以第一个元素为初始值
+func Reduce(ctx *Context, args ...Object) Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError(fmt.Sprintf("reduce 需要 2 或 3 个参数, 得到 %d 个", len(args)))
+	}
+	if err := checkArg("reduce", args, 0, ARRAY); err != nil {
+		return err
+	}
+	if err := checkCallable("reduce", args, 1); err != nil {
+		return err
+	}
+	arr := args[0].(*Array).Value
+	var acc Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(arr) == 0 {
+			return newError("reduce 空数组时必须提供初始值")
+		}
+		acc, arr = arr[0], arr[1:]
+	}
+	for _, element := range arr {
+		val, err := callFun(ctx, args[1], acc, element)
+		if err != nil {
+			return err
+		}
+		acc = val
+	}
+	return acc
+}
+
+// Range range(n) range(start, end) range(start, end, step) 生成整数数组, 不包含 end
+func Range(ctx *Context, args ...Object) Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newError(fmt.Sprintf("range 需要 1 到 3 个参数, 得到 %d 个", len(args)))
+	}
+	nums := make([]int64, len(args))
+	for i := range args {
+		if err := checkArg("range", args, i, INT); err != nil {
+			return err
+		}
+		nums[i] = args[i].(*Integer).Value
+	}
+	start, end, step := int64(0), nums[0], int64(1)
+	if len(nums) > 1 {
+		start, end = nums[0], nums[1]
+	}
+	if len(nums) > 2 {
+		step = nums[2]
+	}
+	if step == 0 {
+		return newError("range 的步长不能为0")
+	}
+	result := []Object{}
+	for i := start; step > 0 && i < end || step < 0 && i > end; i += step {
+		result = append(result, &Integer{Value: i})
+	}
+	return &Array{Value: result}
+}
+
+// Zip 将多个数组按下标组合为 [a[i], b[i], ...], 长度取最短的数组
+func Zip(ctx *Context, args ...Object) Object {
+	if len(args) < 2 {
+		return newError(fmt.Sprintf("zip 至少需要 2 个参数, 得到 %d 个", len(args)))
+	}
+	length := -1
+	for i := range args {
+		if err := checkArg("zip", args, i, ARRAY); err != nil {
+			return err
+		}
+		if n := len(args[i].(*Array).Value); length < 0 || n < length {
+			length = n
+		}
+	}
+	result := make([]Object, length)
+	for i := range result {
+		tuple := make([]Object, len(args))
+		for j, arg := range args {
+			tuple[j] = arg.(*Array).Value[i]
+		}
+		result[i] = &Array{Value: tuple}
+	}
+	return &Array{Value: result}
+}
+
+// joinTypes 用于参数类型错误提示, 例如 "string 或 array"
+func joinTypes(types ...ObjectType) string {
+	str := make([]string, len(types))
+	for i, t := range types {
+		str[i] = t.String()
+	}
+	return strings.Join(str, " 或 ")
+}
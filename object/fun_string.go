@@ -0,0 +1,245 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// checkArgs 检查内置函数的参数数量与类型
+func checkArgs(name string, args []Object, types ...ObjectType) *Error {
+	if len(args) != len(types) {
+		return newError(fmt.Sprintf("%s 需要 %d 个参数, 得到 %d 个", name, len(types), len(args)))
+	}
+	for i, t := range types {
+		if err := checkArg(name, args, i, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func checkArg(name string, args []Object, i int, t ObjectType) *Error {
+	if args[i].Type() != t {
+		return newError(fmt.Sprintf("%s 第 %d 个参数必须是 %s, 得到 %s", name, i+1, t.String(), args[i].Type().String()))
+	}
+	return nil
+}
+
+// checkSequence 检查 contains index_of 的参数, 第一个参数可以是 string 或 array
+func checkSequence(name string, args []Object) *Error {
+	if len(args) != 2 {
+		return newError(fmt.Sprintf("%s 需要 2 个参数, 得到 %d 个", name, len(args)))
+	}
+	if args[0].Type() != STRING {
+		return newError(fmt.Sprintf("%s 第 1 个参数必须是 %s, 得到 %s", name, joinTypes(STRING, ARRAY), args[0].Type().String()))
+	}
+	return checkArg(name, args, 1, STRING)
+}
+func Split(ctx *Context, args ...Object) Object {
+	if err := checkArgs("split", args, STRING, STRING); err != nil {
+		return err
+	}
+	parts := strings.Split(args[0].(*String).Value, args[1].(*String).Value)
+	arr := make([]Object, len(parts))
+	for i, part := range parts {
+		arr[i] = &String{Value: part}
+	}
+	return &Array{Value: arr}
+}
+func Join(ctx *Context, args ...Object) Object {
+	if err := checkArgs("join", args, ARRAY, STRING); err != nil {
+		return err
+	}
+	elements := args[0].(*Array).Value
+	str := make([]string, len(elements))
+	for i, element := range elements {
+		str[i] = element.Inspect()
+	}
+	return &String{Value: strings.Join(str, args[1].(*String).Value)}
+}
+func Trim(ctx *Context, args ...Object) Object {
+	if err := checkArgs("trim", args, STRING); err != nil {
+		return err
+	}
+	return &String{Value: strings.TrimSpace(args[0].(*String).Value)}
+}
+func Upper(ctx *Context, args ...Object) Object {
+	if err := checkArgs("upper", args, STRING); err != nil {
+		return err
+	}
+	return &String{Value: strings.ToUpper(args[0].(*String).Value)}
+}
+func Lower(ctx *Context, args ...Object) Object {
+	if err := checkArgs("lower", args, STRING); err != nil {
+		return err
+	}
+	return &String{Value: strings.ToLower(args[0].(*String).Value)}
+}
+
+// Contains contains(s, sub) 判断子串, contains(arr, val) 判断数组元素
+func Contains(ctx *Context, args ...Object) Object {
+	if len(args) == 2 && args[0].Type() == ARRAY {
+		return boolObject(arrayIndex(args[0].(*Array), args[1]) >= 0)
+	}
+	if err := checkSequence("contains", args); err != nil {
+		return err
+	}
+	return boolObject(strings.Contains(args[0].(*String).Value, args[1].(*String).Value))
+}
+
+// IndexOf 返回子串第一次出现的字符下标或元素在数组中的下标, 不存在时返回 -1
+func IndexOf(ctx *Context, args ...Object) Object {
+	if len(args) == 2 && args[0].Type() == ARRAY {
+		return &Integer{Value: int64(arrayIndex(args[0].(*Array), args[1]))}
+	}
+	if err := checkSequence("index_of", args); err != nil {
+		return err
+	}
+	s := args[0].(*String).Value
+	i := strings.Index(s, args[1].(*String).Value)
+	if i < 0 {
+		return &Integer{Value: -1}
+	}
+	return &Integer{Value: int64(utf8.RuneCountInString(s[:i]))}
+}
+func Replace(ctx *Context, args ...Object) Object {
+	if err := checkArgs("replace", args, STRING, STRING, STRING); err != nil {
+		return err
+	}
+	return &String{Value: strings.ReplaceAll(args[0].(*String).Value, args[1].(*String).Value, args[2].(*String).Value)}
+}
+func StartsWith(ctx *Context, args ...Object) Object {
+	if err := checkArgs("starts_with", args, STRING, STRING); err != nil {
+		return err
+	}
+	return boolObject(strings.HasPrefix(args[0].(*String).Value, args[1].(*String).Value))
+}
+func EndsWith(ctx *Context, args ...Object) Object {
+	if err := checkArgs("ends_with", args, STRING, STRING); err != nil {
+		return err
+	}
+	return boolObject(strings.HasSuffix(args[0].(*String).Value, args[1].(*String).Value))
+}
+func Repeat(ctx *Context, args ...Object) Object {
+	if err := checkArgs("repeat", args, STRING, INT); err != nil {
+		return err
+	}
+	n := args[1].(*Integer).Value
+	if n < 0 {
+		return newError("repeat 的次数不能为负数")
+	}
+	return &String{Value: strings.Repeat(args[0].(*String).Value, int(n))}
+}
+
+// Substr substr(s, start) 或 substr(s, start, length), 按字符计算, 超出结尾的部分被截断
+func Substr(ctx *Context, args ...Object) Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newError(fmt.Sprintf("substr 需要 2 到 3 个参数, 得到 %d 个", len(args)))
+	}
+	for i, t := range []ObjectType{STRING, INT, INT}[:len(args)] {
+		if err := checkArg("substr", args, i, t); err != nil {
+			return err
+		}
+	}
+	runes := []rune(args[0].(*String).Value)
+	start := args[1].(*Integer).Value
+	if start < 0 || start > int64(len(runes)) {
+		return newError(fmt.Sprintf("substr 的开始位置 %d 超出范围", start))
+	}
+	end := int64(len(runes))
+	if len(args) == 3 {
+		length := args[2].(*Integer).Value
+		if length < 0 {
+			return newError(fmt.Sprintf("substr 的长度 %d 不能为负数", length))
+		}
+		if start+length < end {
+			end = start + length
+		}
+	}
+	return &String{Value: string(runes[start:end])}
+}
+
+// Format 按 Go 的格式化规则生成字符串, 例如 format("%s=%d", "a", 1)
+// 支持 %s %v %q %d %x %X %o %b %f %e %g %t 与 %%, 参数的数量与类型必须和格式一致
+func Format(ctx *Context, args ...Object) Object {
+	if len(args) < 1 {
+		return newError("format 至少需要 1 个参数")
+	}
+	if err := checkArg("format", args, 0, STRING); err != nil {
+		return err
+	}
+	verbs, err := formatVerbs(args[0].(*String).Value)
+	if err != nil {
+		return err
+	}
+	if len(verbs) != len(args)-1 {
+		return newError(fmt.Sprintf("format 的格式需要 %d 个参数, 得到 %d 个", len(verbs), len(args)-1))
+	}
+	values := make([]interface{}, len(verbs))
+	for i, verb := range verbs {
+		value, err := formatValue(verb, args, i+1)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	return &String{Value: fmt.Sprintf(args[0].(*String).Value, values...)}
+}
+
+// formatVerbs 依次取出格式中的动词, 只允许标志, 宽度与精度, 不支持 * 与 [n]
+func formatVerbs(format string) ([]rune, *Error) {
+	var verbs []rune
+	runes := []rune(format)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(runes) && strings.ContainsRune("+-# 0123456789.", runes[i]) {
+			i++
+		}
+		if i == len(runes) {
+			return nil, newError("format 的格式不完整")
+		}
+		switch runes[i] {
+		case '%':
+		case 's', 'v', 'q', 'd', 'x', 'X', 'o', 'b', 'f', 'e', 'E', 'g', 'G', 't':
+			verbs = append(verbs, runes[i])
+		default:
+			return nil, newError(fmt.Sprintf("format 不支持 %%%c", runes[i]))
+		}
+	}
+	return verbs, nil
+}
+
+// formatValue 按动词检查第 i 个参数的类型, 并转换为 Go 的值
+func formatValue(verb rune, args []Object, i int) (interface{}, *Error) {
+	arg := args[i]
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b':
+		if err := checkArg("format", args, i, INT); err != nil {
+			return nil, err
+		}
+		return arg.(*Integer).Value, nil
+	case 'f', 'e', 'E', 'g', 'G':
+		f, ok := ToFloat(arg)
+		if !ok {
+			return nil, newError(fmt.Sprintf("format 第 %d 个参数必须是 %s, 得到 %s", i+1, joinTypes(INT, FLOAT), arg.Type().String()))
+		}
+		return f, nil
+	case 't':
+		if err := checkArg("format", args, i, BOOL); err != nil {
+			return nil, err
+		}
+		return arg.(*Bool).Value, nil
+	case 'q':
+		if err := checkArg("format", args, i, STRING); err != nil {
+			return nil, err
+		}
+		return arg.(*String).Value, nil
+	}
+	if str, ok := arg.(*String); ok {
+		return str.Value, nil
+	}
+	return arg.Inspect(), nil
+}
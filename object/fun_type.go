@@ -0,0 +1,104 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// checkOne 检查只有一个参数的内置函数
+func checkOne(name string, args []Object) *Error {
+	if len(args) != 1 {
+		return newError(fmt.Sprintf("%s 需要 1 个参数, 得到 %d 个", name, len(args)))
+	}
+	return nil
+}
+func convertError(name string, arg Object) *Error {
+	return newError(fmt.Sprintf("%s 不能转换 %s 类型", name, arg.Type().String()))
+}
+
+func TypeOf(ctx *Context, args ...Object) Object {
+	if err := checkOne("type", args); err != nil {
+		return err
+	}
+	return &String{Value: args[0].Type().String()}
+}
+
+// ToInt float 向零取整, bool 转为 1 或 0, string 按整数字面量解析
+func ToInt(ctx *Context, args ...Object) Object {
+	if err := checkOne("int", args); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *Integer:
+		return arg
+	case *Float:
+		if math.IsNaN(arg.Value) || arg.Value >= math.MaxInt64 || arg.Value < math.MinInt64 {
+			return newError(fmt.Sprintf("int 无法将 %s 转换为 int", arg.Inspect()))
+		}
+		return &Integer{Value: int64(arg.Value)}
+	case *Bool:
+		if arg.Value {
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case *String:
+		val, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 0, 64)
+		if err != nil {
+			return newError(fmt.Sprintf("int 无法将 %q 转换为 int", arg.Value))
+		}
+		return &Integer{Value: val}
+	}
+	return convertError("int", args[0])
+}
+
+// ToFloatFun int 与 bool 转为 float, string 按数字字面量解析
+func ToFloatFun(ctx *Context, args ...Object) Object {
+	if err := checkOne("float", args); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *Integer:
+		return &Float{Value: float64(arg.Value)}
+	case *Float:
+		return arg
+	case *Bool:
+		if arg.Value {
+			return &Float{Value: 1}
+		}
+		return &Float{Value: 0}
+	case *String:
+		val, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+		if err != nil {
+			return newError(fmt.Sprintf("float 无法将 %q 转换为 float", arg.Value))
+		}
+		return &Float{Value: val}
+	}
+	return convertError("float", args[0])
+}
+
+// ToStr 返回值的字符串形式, 与 println 的输出一致
+func ToStr(ctx *Context, args ...Object) Object {
+	if err := checkOne("str", args); err != nil {
+		return err
+	}
+	if s, ok := args[0].(*String); ok {
+		return s
+	}
+	return &String{Value: args[0].Inspect()}
+}
+
+// ToBool 按 Truthy 的规则转换
+func ToBool(ctx *Context, args ...Object) Object {
+	if err := checkOne("bool", args); err != nil {
+		return err
+	}
+	return boolObject(Truthy(args[0]))
+}
+func IsNull(ctx *Context, args ...Object) Object {
+	if err := checkOne("is_null", args); err != nil {
+		return err
+	}
+	return boolObject(args[0].Type() == NULL)
+}
@@ -2,11 +2,48 @@ package object
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 )
 
+// HashKey 可作为 hash 键的值, 相同类型且相同值的对象得到相同的 HashKey
+// string 直接以内容作为键, 不同的字符串不会冲突
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+	Str   string
+}
+
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: INT, Value: uint64(i.Value)}
+}
+func (b *Bool) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Type: BOOL, Value: 1}
+	}
+	return HashKey{Type: BOOL, Value: 0}
+}
+func (s *String) HashKey() HashKey {
+	return HashKey{Type: STRING, Str: s.Value}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
 type Hash struct {
-	Value map[Object]Object
+	Pairs map[HashKey]*HashPair
+	keys  []HashKey //插入顺序
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: map[HashKey]*HashPair{}}
 }
 
 func (h *Hash) Type() ObjectType {
@@ -17,10 +54,56 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	var arr []string
 	out.WriteString("{")
-	for object, o := range h.Value {
-		arr = append(arr, object.Inspect()+":"+o.Inspect())
+	for _, pair := range h.Iter() {
+		arr = append(arr, pair.Key.Inspect()+":"+pair.Value.Inspect())
 	}
 	out.WriteString(strings.Join(arr, ","))
-	out.WriteString("]")
+	out.WriteString("}")
 	return out.String()
 }
+
+// Get 查找 key, 不存在时返回 NULL_, key 不可 hash 时返回错误
+func (h *Hash) Get(key Object) (Object, *Error) {
+	k, ok := key.(Hashable)
+	if !ok {
+		return nil, unhashable(key)
+	}
+	pair, ok := h.Pairs[k.HashKey()]
+	if !ok {
+		return NULL_, nil
+	}
+	return pair.Value, nil
+}
+
+// Set 设置 key 对应的值, key 不可 hash 时返回错误
+func (h *Hash) Set(key Object, val Object) *Error {
+	k, ok := key.(Hashable)
+	if !ok {
+		return unhashable(key)
+	}
+	hashKey := k.HashKey()
+	if pair, ok := h.Pairs[hashKey]; ok {
+		pair.Value = val
+		return nil
+	}
+	h.Pairs[hashKey] = &HashPair{Key: key, Value: val}
+	h.keys = append(h.keys, hashKey)
+	return nil
+}
+
+func (h *Hash) Len() int {
+	return len(h.keys)
+}
+
+// Iter 按插入顺序返回所有键值对
+func (h *Hash) Iter() []*HashPair {
+	pairs := make([]*HashPair, 0, len(h.keys))
+	for _, key := range h.keys {
+		pairs = append(pairs, h.Pairs[key])
+	}
+	return pairs
+}
+
+func unhashable(key Object) *Error {
+	return &Error{Msg: fmt.Sprintf("%s 类型不能作为 hash 的键", key.Type().String())}
+}
@@ -0,0 +1,118 @@
+package object
+
+import "fmt"
+
+// Index 取 array string hash 的元素, array 与 string 越界时返回 NULL_, string 按字符计算下标
+func Index(left Object, index Object) Object {
+	switch l := left.(type) {
+	case *Array:
+		i, ok := index.(*Integer)
+		if !ok {
+			return newError("数组的索引只能int类型")
+		}
+		if i.Value < 0 || i.Value >= int64(len(l.Value)) {
+			return NULL_
+		}
+		return l.Value[i.Value]
+	case *String:
+		i, ok := index.(*Integer)
+		if !ok {
+			return newError("字符串的索引只能int类型")
+		}
+		runes := []rune(l.Value)
+		if i.Value < 0 || i.Value >= int64(len(runes)) {
+			return NULL_
+		}
+		return &String{Value: string(runes[i.Value])}
+	case *Hash:
+		val, err := l.Get(index)
+		if err != nil {
+			return err
+		}
+		return val
+	case *Error:
+		if name, ok := index.(*String); ok {
+			if val, ok := l.Field(name.Value); ok {
+				return val
+			}
+		}
+		return newError(fmt.Sprintf("error 没有 %s 字段", index.Inspect()))
+	}
+	return newError(fmt.Sprintf("%s 类型不支持索引操作", left.Type().String()))
+}
+
+// SetIndex 设置 array hash 的元素
+func SetIndex(container Object, key Object, val Object) *Error {
+	switch c := container.(type) {
+	case *Array:
+		i, ok := key.(*Integer)
+		if !ok {
+			return newError("数组索引只能是数字类型")
+		}
+		if i.Value < 0 || i.Value >= int64(len(c.Value)) {
+			return newError(fmt.Sprintf("数组索引越界 %d", i.Value))
+		}
+		c.Value[i.Value] = val
+		return nil
+	case *Hash:
+		return c.Set(key, val)
+	}
+	return newError("只能对数组或hash的元素赋值")
+}
+
+// Slice 截取 array string 的 [start:end], start end 为 NULL_ 时表示省略.
+// 负数下标从末尾开始计算, 超出范围时截断到边界
+func Slice(left Object, start Object, end Object) Object {
+	switch l := left.(type) {
+	case *Array:
+		s, e, err := sliceBounds(len(l.Value), start, end)
+		if err != nil {
+			return err
+		}
+		arr := make([]Object, e-s)
+		copy(arr, l.Value[s:e])
+		return &Array{Value: arr}
+	case *String:
+		runes := []rune(l.Value)
+		s, e, err := sliceBounds(len(runes), start, end)
+		if err != nil {
+			return err
+		}
+		return &String{Value: string(runes[s:e])}
+	}
+	return newError(fmt.Sprintf("%s 类型不支持切片操作", left.Type().String()))
+}
+func sliceBounds(length int, start Object, end Object) (int, int, *Error) {
+	s, err := sliceBound(length, start, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	e, err := sliceBound(length, end, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	if s > e {
+		s = e
+	}
+	return s, e, nil
+}
+func sliceBound(length int, bound Object, def int) (int, *Error) {
+	if bound == NULL_ {
+		return def, nil
+	}
+	i, ok := bound.(*Integer)
+	if !ok {
+		return 0, newError("切片的下标只能int类型")
+	}
+	n := i.Value
+	if n < 0 {
+		n += int64(length)
+	}
+	if n < 0 {
+		return 0, nil
+	}
+	if n > int64(length) {
+		return length, nil
+	}
+	return int(n), nil
+}
@@ -0,0 +1,46 @@
+package object
+
+import (
+	"fmt"
+	"hek/token"
+)
+
+// Break 在 Eval 中向外层传递 break, 直到被对应的循环接收
+type Break struct {
+	Label string
+	Pos   token.Position
+}
+
+func (b *Break) Type() ObjectType {
+	return BREAK
+}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+// Continue 在 Eval 中向外层传递 continue, 直到被对应的循环接收
+type Continue struct {
+	Label string
+	Pos   token.Position
+}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE
+}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+// loopControlError 循环外的 break continue, 信息与编译器一致
+func loopControlError(obj Object) *Error {
+	var pos token.Position
+	switch o := obj.(type) {
+	case *Break:
+		pos = o.Pos
+	case *Continue:
+		pos = o.Pos
+	}
+	return newError(fmt.Sprintf("%s: %s 只能在循环中使用", pos, obj.Inspect()))
+}
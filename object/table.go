@@ -6,6 +6,45 @@ var table = []*InternalName{
 	{Name: "echo", Fun: &InternalFun{Fun_: Echo}},
 	{Name: "put", Fun: &InternalFun{Fun_: Put}},
 	{Name: "str_rev", Fun: &InternalFun{Fun_: StringReversal}},
+	{Name: "args", Fun: &InternalFun{Fun_: Args}},
+	{Name: "split", Fun: &InternalFun{Fun_: Split}},
+	{Name: "join", Fun: &InternalFun{Fun_: Join}},
+	{Name: "trim", Fun: &InternalFun{Fun_: Trim}},
+	{Name: "upper", Fun: &InternalFun{Fun_: Upper}},
+	{Name: "lower", Fun: &InternalFun{Fun_: Lower}},
+	{Name: "contains", Fun: &InternalFun{Fun_: Contains}},
+	{Name: "index_of", Fun: &InternalFun{Fun_: IndexOf}},
+	{Name: "replace", Fun: &InternalFun{Fun_: Replace}},
+	{Name: "starts_with", Fun: &InternalFun{Fun_: StartsWith}},
+	{Name: "ends_with", Fun: &InternalFun{Fun_: EndsWith}},
+	{Name: "repeat", Fun: &InternalFun{Fun_: Repeat}},
+	{Name: "substr", Fun: &InternalFun{Fun_: Substr}},
+	{Name: "format", Fun: &InternalFun{Fun_: Format}},
+	{Name: "sprintf", Fun: &InternalFun{Fun_: Format}},
+	{Name: "first", Fun: &InternalFun{Fun_: First}},
+	{Name: "last", Fun: &InternalFun{Fun_: Last}},
+	{Name: "rest", Fun: &InternalFun{Fun_: Rest}},
+	{Name: "push", Fun: &InternalFun{Fun_: Push}},
+	{Name: "pop", Fun: &InternalFun{Fun_: Pop}},
+	{Name: "insert", Fun: &InternalFun{Fun_: Insert}},
+	{Name: "remove", Fun: &InternalFun{Fun_: Remove}},
+	{Name: "reverse", Fun: &InternalFun{Fun_: Reverse}},
+	{Name: "concat", Fun: &InternalFun{Fun_: Concat}},
+	{Name: "sort", Fun: &InternalFun{Fun_: Sort}},
+	{Name: "sort_by", Fun: &InternalFun{Fun_: SortBy}},
+	{Name: "map", Fun: &InternalFun{Fun_: Map}},
+	{Name: "filter", Fun: &InternalFun{Fun_: Filter}},
+	{Name: "reduce", Fun: &InternalFun{Fun_: Reduce}},
+	{Name: "range", Fun: &InternalFun{Fun_: Range}},
+	{Name: "zip", Fun: &InternalFun{Fun_: Zip}},
+	{Name: "type", Fun: &InternalFun{Fun_: TypeOf}},
+	{Name: "int", Fun: &InternalFun{Fun_: ToInt}},
+	{Name: "str", Fun: &InternalFun{Fun_: ToStr}},
+	{Name: "bool", Fun: &InternalFun{Fun_: ToBool}},
+	{Name: "float", Fun: &InternalFun{Fun_: ToFloatFun}},
+	{Name: "is_null", Fun: &InternalFun{Fun_: IsNull}},
+	{Name: "error", Fun: &InternalFun{Fun_: NewErrorFun}},
+	{Name: "is_error", Fun: &InternalFun{Fun_: IsErrorFun}},
 }
 
 var funName map[string]int
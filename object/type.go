@@ -14,19 +14,31 @@ const (
 	ARRAY
 	HASH
 	CompiledFun
+	BREAK
+	CONTINUE
+	FLOAT
 )
 
+// typeString 类型名, type(x) 的返回值, 解释器与 VM 中的函数都是 function
 var typeString = map[ObjectType]string{
-	INT:    "int",
-	BOOL:   "bool",
-	NULL:   "null",
-	STRING: "string",
-	ARRAY:  "array",
-	HASH:   "hash",
+	INT:         "int",
+	FLOAT:       "float",
+	BOOL:        "bool",
+	NULL:        "null",
+	STRING:      "string",
+	ARRAY:       "array",
+	HASH:        "hash",
+	FUN:         "function",
+	CompiledFun: "function",
+	BUILTFun:    "builtin",
+	ERROR:       "error",
+	RETURN:      "return",
+	BREAK:       "break",
+	CONTINUE:    "continue",
 }
 
 func (o ObjectType) String() string {
 	return typeString[o]
 }
 
-type InsideFun func(ages ...Object) Object
+type InsideFun func(ctx *Context, args ...Object) Object
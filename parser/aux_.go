@@ -6,16 +6,27 @@ import (
 )
 
 var precedence = map[token.Type]int{
-	token.EQ:       EQUALS,
-	token.NotEq:    EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: LBRACKET,
+	token.OR:         LOGICOR,
+	token.AND:        LOGICAND,
+	token.EQ:         EQUALS,
+	token.NotEq:      EQUALS,
+	token.BitOr:      BITOR,
+	token.BitXor:     BITXOR,
+	token.BitAnd:     BITAND,
+	token.LT:         LESSGREATER,
+	token.GT:         LESSGREATER,
+	token.LtEq:       LESSGREATER,
+	token.GtEq:       LESSGREATER,
+	token.ShiftLeft:  SHIFT,
+	token.ShiftRight: SHIFT,
+	token.PLUS:       SUM,
+	token.MINUS:      SUM,
+	token.SLASH:      PRODUCT,
+	token.ASTERISK:   PRODUCT,
+	token.PERCENT:    PRODUCT,
+	token.LPAREN:     CALL,
+	token.LBRACKET:   LBRACKET,
+	token.DOT:        LBRACKET,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -45,13 +56,26 @@ func (p *Parser) expectPeek(t token.Type) bool {
 	return false
 }
 func (p *Parser) peekErrors(t token.Type) {
-	p.errors = append(p.errors, fmt.Sprintf("peek token is '%s' not is '%s'", p.peekToken.Type.ToString(), t.ToString()))
+	p.errors = append(p.errors, fmt.Sprintf("%s: peek token is '%s' not is '%s'", p.peekToken.Pos, p.peekToken.Type.ToString(), t.ToString()))
 }
 func (p *Parser) integerLiteralErrors(t token.Token) {
-	p.errors = append(p.errors, fmt.Sprintf("%s not int type", t.Literal))
+	p.errors = append(p.errors, fmt.Sprintf("%s: %s not int type", t.Pos, t.Literal))
 }
+func (p *Parser) noPrefixParseFunError(t token.Token) {
+	if t.Type == token.ILLEGAL {
+		//词法错误已经由 lexer 记录
+		return
+	}
+	p.errors = append(p.errors, fmt.Sprintf("%s: unexpected token '%s'", t.Pos, t.Literal))
+}
+func (p *Parser) floatLiteralErrors(t token.Token) {
+	p.errors = append(p.errors, fmt.Sprintf("%s: %s not float type", t.Pos, t.Literal))
+}
+
+// Errors 返回词法错误与语法错误
 func (p *Parser) Errors() []string {
-	return p.errors
+	errs := append([]string{}, p.l.Errors()...)
+	return append(errs, p.errors...)
 }
 func (p *Parser) registerPrefixFun(types token.Type, fun prefixParseFun) {
 	p.prefixParseFus[types] = fun
@@ -63,14 +87,17 @@ func (p *Parser) registerFunALL() {
 	//prefix
 	p.registerPrefixFun(token.IDENT, p.parseIdentifier)
 	p.registerPrefixFun(token.INT, p.parseIntegerLiteral)
+	p.registerPrefixFun(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefixFun(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefixFun(token.BANG, p.parsePrefixExpression)
+	p.registerPrefixFun(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefixFun(token.TRUE, p.parseBoolExpression)
 	p.registerPrefixFun(token.FALSE, p.parseBoolExpression)
 	p.registerPrefixFun(token.LPAREN, p.parseGroupExpression)
 	p.registerPrefixFun(token.IF, p.parseIFExpression)
 	p.registerPrefixFun(token.FUNCTION, p.parseFunExpression)
 	p.registerPrefixFun(token.String, p.parseString)
+	p.registerPrefixFun(token.StringHead, p.parseInterpolatedString)
 	p.registerPrefixFun(token.LBRACKET, p.parseArrayExpression)
 	p.registerPrefixFun(token.LBRACE, p.parseHashExpression)
 	p.registerPrefixFun(token.FOR, p.parseForExpression)
@@ -84,6 +111,17 @@ func (p *Parser) registerFunALL() {
 	p.registerInfixFun(token.NotEq, p.parseInfixExpression)
 	p.registerInfixFun(token.MINUS, p.parseInfixExpression)
 	p.registerInfixFun(token.PLUS, p.parseInfixExpression)
+	p.registerInfixFun(token.LtEq, p.parseInfixExpression)
+	p.registerInfixFun(token.GtEq, p.parseInfixExpression)
+	p.registerInfixFun(token.PERCENT, p.parseInfixExpression)
+	p.registerInfixFun(token.BitAnd, p.parseInfixExpression)
+	p.registerInfixFun(token.BitOr, p.parseInfixExpression)
+	p.registerInfixFun(token.BitXor, p.parseInfixExpression)
+	p.registerInfixFun(token.ShiftLeft, p.parseInfixExpression)
+	p.registerInfixFun(token.ShiftRight, p.parseInfixExpression)
+	p.registerInfixFun(token.AND, p.parseInfixExpression)
+	p.registerInfixFun(token.OR, p.parseInfixExpression)
 	p.registerInfixFun(token.LPAREN, p.parseInfixCallExpression)
 	p.registerInfixFun(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfixFun(token.DOT, p.parseDotExpression)
 }
@@ -3,11 +3,17 @@ package parser
 const (
 	_ int = iota
 	LOWEST
+	LOGICOR     //||
+	LOGICAND    //&&
+	BITOR       //|
+	BITXOR      //^
+	BITAND      //&
 	EQUALS      //== or !=
-	LESSGREATER // > or <
+	LESSGREATER // > or < or >= or <=
+	SHIFT       //<< >>
 	SUM         //+ -
-	PRODUCT     //* /
-	PREFIX      //-x os !x
+	PRODUCT     //* / %
+	PREFIX      //-x os !x or ~x
 	CALL        //CALL test(x,y)
 	LBRACKET    // [
 )
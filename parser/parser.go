@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"hek/ast"
 	"hek/lexer"
 	"hek/token"
@@ -53,12 +54,25 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.IDENT:
+		if p.peekTokenIs(token.COLON) {
+			return p.parseLabelStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+	stmt := &ast.LetStatement{Token: p.curToken, Doc: p.curToken.Doc}
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -73,20 +87,122 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
-	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.EOF) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
+	// 没有返回值的 return, 后面直接是 ; } 或文件结尾
+	if p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		return stmt
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		return stmt
+	}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+	if p.peekLabel() {
+		p.nextToken()
+		stmt.Label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// peekLabel break continue 后面同一行的标识符才是标签
+func (p *Parser) peekLabel() bool {
+	return p.peekTokenIs(token.IDENT) && p.peekToken.Pos.Line == p.curToken.Pos.Line
+}
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+	if p.peekLabel() {
+		p.nextToken()
+		stmt.Label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseTryStatement 解析 try {} catch (e) {} finally {}, catch 的参数可以省略
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.errors = append(p.errors, fmt.Sprintf("%s: try without catch or finally", stmt.Pos()))
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
-	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.EOF) {
+	if stmt.Value == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
+
+// parseLabelStatement 解析 outer: for(...) {...}, 标签只能用于 for
+func (p *Parser) parseLabelStatement() ast.Statement {
+	label := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.nextToken()
+	if !p.expectPeek(token.FOR) {
+		return nil
+	}
+	stmt := p.parseExpressionStatement()
+	if for_, ok := stmt.Expression.(*ast.ForExpression); ok {
+		for_.Label = label
+	}
+	return stmt
+}
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 	stmt.Expression = p.parseExpression(LOWEST)
@@ -100,6 +216,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFus[p.curToken.Type]
 
 	if prefix == nil {
+		p.noPrefixParseFunError(p.curToken)
 		return nil
 	}
 	left := prefix()
@@ -125,7 +242,7 @@ func (p *Parser) parseIdentifier() ast.Expression {
 		p.nextToken()
 		exp.Value = p.parseExpression(LOWEST)
 		return exp
-	} else if p.peekTokenIs(token.TwoPlus) || p.peekTokenIs(token.TwoPlus) {
+	} else if p.peekTokenIs(token.TwoPlus) || p.peekTokenIs(token.TwoMinus) {
 		exp := &ast.SuffixExpression{Left: &ast.Identifier{
 			Token: p.curToken,
 			Value: p.curToken.Literal,
@@ -146,6 +263,16 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit.Value = val
 	return lit
 }
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	val, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.floatLiteralErrors(p.curToken)
+		return nil
+	}
+	lit.Value = val
+	return lit
+}
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	exp := &ast.PrefixExpression{
 		Token: p.curToken,
@@ -222,7 +349,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	return block
 }
 func (p *Parser) parseFunExpression() ast.Expression {
-	exp := &ast.FunExpression{Token: p.curToken}
+	exp := &ast.FunExpression{Token: p.curToken, Doc: p.curToken.Doc}
 
 	if p.peekTokenIs(token.IDENT) {
 		//函数命名
@@ -236,40 +363,57 @@ func (p *Parser) parseFunExpression() ast.Expression {
 		p.nextToken()
 	}
 
-	exp.Params = p.parseFunParams()
-
+	if !p.parseFunParams(exp) {
+		return nil
+	}
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 	exp.Block = p.parseBlockStatement()
 	return exp
 }
-func (p *Parser) parseFunParams() []*ast.Identifier {
-	var ids []*ast.Identifier
 
+// parseFunParams 解析 (a, b = 1, ...rest), 默认值参数之后不能再有普通参数, ...rest 只能是最后一个参数
+func (p *Parser) parseFunParams(exp *ast.FunExpression) bool {
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return nil
+		return true
 	}
-	p.nextToken()
-	ids = append(ids, &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	})
-
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken() //把符号 , 移到 当前token位置
+	for {
 		p.nextToken() //把 参数 移到 当前token位置
-		ids = append(ids, &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		})
-	}
-	if !p.peekTokenIs(token.RPAREN) {
-		return nil
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			exp.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.peekTokenIs(token.RPAREN) {
+				p.errors = append(p.errors, fmt.Sprintf("%s: rest parameter '%s' must be the last parameter", p.curToken.Pos, p.curToken.Literal))
+				return false
+			}
+			break
+		}
+		if !p.curTokenIs(token.IDENT) {
+			p.errors = append(p.errors, fmt.Sprintf("%s: unexpected token '%s' in parameter list", p.curToken.Pos, p.curToken.Literal))
+			return false
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		var value ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			value = p.parseExpression(LOWEST)
+		} else if len(exp.Defaults) > 0 && exp.Defaults[len(exp.Defaults)-1] != nil {
+			p.errors = append(p.errors, fmt.Sprintf("%s: parameter '%s' without default value follows a parameter with default value", param.Pos(), param.Value))
+			return false
+		}
+		exp.Params = append(exp.Params, param)
+		exp.Defaults = append(exp.Defaults, value)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken() //把符号 , 移到 当前token位置
 	}
-	p.nextToken()
-	return ids
+	return p.expectPeek(token.RPAREN)
 }
 func (p *Parser) parseCallExpression() ast.Expression {
 	return nil
@@ -290,25 +434,96 @@ func (p *Parser) parseCallParams() []ast.Expression {
 		return nil
 	}
 	p.nextToken()
-	exps = append(exps, p.parseExpression(LOWEST))
+	exps = append(exps, p.parseCallParam())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken() //把符号 , 移到 当前token位置
 		p.nextToken() //把 参数 移到 当前token位置
-		exps = append(exps, p.parseExpression(LOWEST))
+		exps = append(exps, p.parseCallParam())
 	}
 	if !p.peekTokenIs(token.RPAREN) {
 		return nil
 	}
 	p.nextToken()
+	p.checkNamedArgs(exps)
 	return exps
 }
+
+// parseCallParam 解析一个调用参数, ...arr 展开数组, name = value 是命名参数
+func (p *Parser) parseCallParam() ast.Expression {
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+		exp := &ast.NamedArgument{Token: p.curToken, Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		p.nextToken()
+		p.nextToken()
+		exp.Value = p.parseExpression(LOWEST)
+		return exp
+	}
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+	exp := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// checkNamedArgs 命名参数只能在普通参数之后, 不能重复, 也不能与展开参数一起使用
+func (p *Parser) checkNamedArgs(exps []ast.Expression) {
+	names := map[string]bool{}
+	var spread ast.Expression
+	for _, exp := range exps {
+		named, ok := exp.(*ast.NamedArgument)
+		if !ok {
+			if _, isSpread := exp.(*ast.SpreadExpression); isSpread {
+				spread = exp
+			}
+			if len(names) > 0 && exp != nil {
+				p.errors = append(p.errors, fmt.Sprintf("%s: positional argument after named argument", exp.Pos()))
+			}
+			continue
+		}
+		if names[named.Name.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("%s: duplicate named argument %s", named.Pos(), named.Name.Value))
+		}
+		names[named.Name.Value] = true
+	}
+	if spread != nil && len(names) > 0 {
+		p.errors = append(p.errors, fmt.Sprintf("%s: named arguments cannot be combined with spread arguments", spread.Pos()))
+	}
+}
 func (p *Parser) parseString() ast.Expression {
 	return &ast.StringExpression{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
 }
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	exp := &ast.InterpolatedString{Token: p.curToken}
+	for {
+		if p.curToken.Literal != "" {
+			exp.Parts = append(exp.Parts, &ast.StringExpression{Token: p.curToken, Value: p.curToken.Literal})
+		}
+		if p.curTokenIs(token.StringTail) {
+			return exp
+		}
+		p.nextToken()
+		if p.curTokenIs(token.StringMid) || p.curTokenIs(token.StringTail) {
+			p.errors = append(p.errors, fmt.Sprintf("%s: empty expression in string interpolation", p.curToken.Pos))
+			return nil
+		}
+		part := p.parseExpression(LOWEST)
+		if part == nil {
+			return nil
+		}
+		exp.Parts = append(exp.Parts, part)
+		if !p.peekTokenIs(token.StringMid) && !p.expectPeek(token.StringTail) {
+			return nil
+		}
+		if p.peekTokenIs(token.StringMid) {
+			p.nextToken()
+		}
+	}
+}
 func (p *Parser) parseArrayExpression() ast.Expression {
 	exp := &ast.ArrayExpression{Token: p.curToken}
 	exp.Value = p.parseParamList(token.RBRACKET)
@@ -340,10 +555,16 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 		Token: p.curToken,
 		Left:  left,
 	}
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, nil)
+	}
 	p.nextToken()
 
 	exp.Index = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, exp.Index)
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
@@ -357,13 +578,41 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	}
 	return exp
 }
-func (p *Parser) parseHashExpression() ast.Expression {
-	exp := &ast.HashExpression{
-		Token: p.curToken,
-		Value: map[ast.Expression]ast.Expression{},
+
+// parseDotExpression a.name 等价于 a["name"]
+func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Index = &ast.StringExpression{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		ass := &ast.AssigExpression{Name: exp}
+		ass.Value = p.parseExpression(LOWEST)
+		return ass
 	}
+	return exp
+}
+
+// parseSliceExpression 从 : 之前开始解析 a[start:end] 的剩余部分
+func (p *Parser) parseSliceExpression(tok token.Token, left ast.Expression, start ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
 	p.nextToken()
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return exp
+}
+func (p *Parser) parseHashExpression() ast.Expression {
+	exp := &ast.HashExpression{Token: p.curToken}
 	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
 		key := p.parseExpression(LOWEST)
 
 		if !p.expectPeek(token.COLON) {
@@ -371,16 +620,14 @@ func (p *Parser) parseHashExpression() ast.Expression {
 		}
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
-		exp.Value[key] = value
+		exp.Pairs = append(exp.Pairs, &ast.HashPair{Key: key, Value: value})
 
-		if p.peekTokenIs(token.RBRACE) {
-			break
-		}
-		if !p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.EOF) {
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
-		p.nextToken()
-		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
 	}
 	return exp
 }
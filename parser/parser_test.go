@@ -2,7 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"hek/ast"
 	"hek/lexer"
+	"strings"
 	"testing"
 )
 
@@ -30,3 +32,114 @@ func TestNewParser(t *testing.T) {
 		fmt.Println(err)
 	}
 }
+
+func TestStatementEnd(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"let a = 1\nlet b = 2", 2},
+		{"let f = fun() { return a }\nf()", 2},
+		{"let f = fun() { return }; f()", 2},
+		{"let f = fun() { return; }", 1},
+		{"return", 1},
+	}
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: %v", tt.input, p.Errors())
+		}
+		if got := len(program.Statements); got != tt.want {
+			t.Errorf("%q: got %d statements, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDocComment(t *testing.T) {
+	input := "// 计数器\nlet count = 0;\n\n/* 加一 */\nfun inc() { count = count + 1 }"
+
+	p := NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatal(p.Errors())
+	}
+	if len(program.Statements) != 2 {
+		t.Fatalf("statements len %d, want 2", len(program.Statements))
+	}
+	let, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok || let.Doc != "计数器" {
+		t.Errorf("let doc = %q, want %q", let.Doc, "计数器")
+	}
+	fun, ok := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunExpression)
+	if !ok || fun.Doc != "加一" {
+		t.Errorf("fun doc = %q, want %q", fun.Doc, "加一")
+	}
+}
+
+func TestInterpolationErrors(t *testing.T) {
+	tests := []string{
+		`"a ${} b"`,
+		`"a ${1 b"`,
+		`"a ${1`,
+	}
+	for _, input := range tests {
+		p := NewParser(lexer.NewLexer(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected parse errors", input)
+		}
+	}
+}
+
+func TestFunParams(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`fun(a, b = 2, ...rest) {}`, "fun fun(a,b=2,...rest)"},
+		{`f(1, ...arr)`, "f(1,...arr)"},
+		{`f(1, c = 9, b = a)`, "f(1,c=9,b=a)"},
+	}
+	for _, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: %v", tt.input, p.Errors())
+		}
+		if got := program.String(); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+	for _, input := range []string{`fun(a = 1, b) {}`, `fun(...rest, a) {}`, `fun(1) {}`, `f(a = 1, 2)`, `f(a = 1, a = 2)`, `f(...arr, a = 1)`} {
+		p := NewParser(lexer.NewLexer(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected parse errors", input)
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	input := `try { f() } catch (e) { g(e) } finally { h() }; throw "x";`
+	p := NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("%v", p.Errors())
+	}
+	if len(program.Statements) != 2 {
+		t.Fatalf("got %d statements", len(program.Statements))
+	}
+	try, ok := program.Statements[0].(*ast.TryStatement)
+	if !ok || try.Param.Value != "e" || try.Catch == nil || try.Finally == nil {
+		t.Errorf("statement 0 = %s", program.Statements[0])
+	}
+	if _, ok := program.Statements[1].(*ast.ThrowStatement); !ok {
+		t.Errorf("statement 1 = %s", program.Statements[1])
+	}
+	p = NewParser(lexer.NewLexer(`try { f() }`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("try without catch or finally: expected parse errors")
+	}
+}
@@ -0,0 +1,42 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"hek/compiler"
+	"hek/lexer"
+	"hek/parser"
+	"hek/vm"
+	"io"
+	"os"
+	"strings"
+)
+
+// RunFile 读取并执行一个 hek 脚本文件, args 为传给脚本的参数, 输出写到 out
+func RunFile(path string, args []string, out io.Writer) error {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	l := lexer.NewLexerFile(path, string(buf))
+	p := parser.NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return errors.New("err: " + strings.Join(p.Errors(), "\nerr: "))
+	}
+
+	com := compiler.NewCompile()
+	err = com.Compile(program)
+	if err != nil {
+		return fmt.Errorf("compile err: %s", err)
+	}
+
+	vm_ := vm.NewVM(com.ByteCode())
+	vm_.Context().SetArgs(args)
+	vm_.Context().Out = out
+	err = vm_.Run()
+	if err != nil {
+		return fmt.Errorf("vm err: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		args   []string
+		out    string
+		err    string
+	}{
+		{"ok.hek", `echo("hi"); println(1 + 2)`, nil, "hi\n3\n", ""},
+		{"args.hek", `echo(len(args())); echo(args())`, []string{"a", "b c"}, "2\n[a,b c]\n", ""},
+		{"noargs.hek", `echo(args())`, nil, "[]\n", ""},
+		{"throw.hek", "echo(1)\nthrow \"boom\"", nil, "1\n", "vm err: boom\n\tat main (throw.hek:2:1, ip 0013)"},
+		{"parse.hek", "let = 1", nil, "", "err: parse.hek:1:5: peek token is '=' not is 'IDENT'\nerr: parse.hek:1:5: unexpected token '='"},
+		{"undefined.hek", "x", nil, "", "compile err: undefined.hek:1:1: 使用了未定义的变量 x"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		err := RunFile(path, tt.args, &out)
+		if got := out.String(); got != tt.out {
+			t.Errorf("%s output = %q, want %q", tt.name, got, tt.out)
+		}
+		got := ""
+		if err != nil {
+			// 错误里带着临时目录, 只比较文件名之后的部分
+			got = strings.ReplaceAll(err.Error(), dir+string(filepath.Separator), "")
+		}
+		if got != tt.err {
+			t.Errorf("%s err = %q, want %q", tt.name, got, tt.err)
+		}
+	}
+	if err := RunFile(filepath.Join(dir, "missing.hek"), nil, &bytes.Buffer{}); err == nil {
+		t.Errorf("missing file: want error")
+	}
+}
@@ -52,6 +52,7 @@ func Start(in io.Reader, out io.Writer) {
 		}
 		consts = com.ByteCode().Constants
 		vm_ := vm.NewVMCache(com.ByteCode(), golbal)
+		vm_.Context().Out = out
 
 		err = vm_.Run()
 		if err != nil {
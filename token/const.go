@@ -19,9 +19,20 @@ const (
 	NotEq    // !=
 	TwoPlus
 	TwoMinus
+	AND // &&
+	OR  // ||
 
-	LT //<
-	GT // >
+	LT         //<
+	GT         // >
+	LtEq       //<=
+	GtEq       //>=
+	PERCENT    //%
+	BitAnd     //&
+	BitOr      //|
+	BitXor     //^
+	ShiftLeft  //<<
+	ShiftRight //>>
+	TILDE      //~
 	//分隔符
 	COMMA     //,
 	SEMICOLON //;
@@ -35,6 +46,8 @@ const (
 	LBRACKET //[
 	RBRACKET //]
 	COLON    //:
+	ELLIPSIS //...
+	DOT      //.
 	//关键字
 
 	FUNCTION //fun
@@ -47,7 +60,15 @@ const (
 	FOR      //for
 	BREAK    //break
 	CONTINUE //continue
+	TRY      //try
+	CATCH    //catch
+	FINALLY  //finally
+	THROW    //throw
 	//类型
 	INT
+	FLOAT
 	String
+	StringHead //"abc${  插值字符串第一段
+	StringMid  //}abc${  插值字符串中间段
+	StringTail //}abc"   插值字符串最后一段
 )
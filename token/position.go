@@ -0,0 +1,21 @@
+package token
+
+import "fmt"
+
+// Position 源码位置, Line 与 Column 从 1 开始
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
@@ -4,6 +4,8 @@ type Type int
 type Token struct {
 	Type    Type
 	Literal string
+	Pos     Position
+	Doc     string //紧贴在 token 之前的注释
 }
 
 var keywords = map[string]Type{
@@ -17,41 +19,62 @@ var keywords = map[string]Type{
 	"for":      FOR,
 	"break":    BREAK,
 	"continue": CONTINUE,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
 }
 var typeWords = map[Type]string{
-	LET:       "let",
-	INT:       "int",
-	IF:        "if",
-	ELSE:      "else",
-	PLUS:      "+",
-	MINUS:     "-",
-	ASSIGN:    "=",
-	BANG:      "!",
-	ASTERISK:  "*",
-	SLASH:     "/",
-	EQ:        "==",
-	NotEq:     "!=",
-	LT:        "<",
-	GT:        ">",
-	COMMA:     ",",
-	SEMICOLON: ";",
-	LPAREN:    "(",
-	RPAREN:    ")",
-	LBRACE:    "{",
-	RBRACE:    "}",
-	FUNCTION:  "fun",
-	RETURN:    "return",
-	FALSE:     "false",
-	TRUE:      "true",
-	EOF:       "EOF",
-	ILLEGAL:   "ILLEGAL",
-	IDENT:     "IDENT",
-	String:    "string",
-	FOR:       "for",
-	BREAK:     "break",
-	CONTINUE:  "continue",
-	TwoPlus:   "++",
-	TwoMinus:  "--",
+	LET:        "let",
+	INT:        "int",
+	IF:         "if",
+	ELSE:       "else",
+	PLUS:       "+",
+	MINUS:      "-",
+	ASSIGN:     "=",
+	BANG:       "!",
+	ASTERISK:   "*",
+	SLASH:      "/",
+	EQ:         "==",
+	NotEq:      "!=",
+	LT:         "<",
+	GT:         ">",
+	COMMA:      ",",
+	SEMICOLON:  ";",
+	LPAREN:     "(",
+	RPAREN:     ")",
+	LBRACE:     "{",
+	RBRACE:     "}",
+	FUNCTION:   "fun",
+	RETURN:     "return",
+	FALSE:      "false",
+	TRUE:       "true",
+	EOF:        "EOF",
+	ILLEGAL:    "ILLEGAL",
+	IDENT:      "IDENT",
+	String:     "string",
+	FOR:        "for",
+	BREAK:      "break",
+	CONTINUE:   "continue",
+	TRY:        "try",
+	CATCH:      "catch",
+	FINALLY:    "finally",
+	THROW:      "throw",
+	TwoPlus:    "++",
+	TwoMinus:   "--",
+	AND:        "&&",
+	OR:         "||",
+	LtEq:       "<=",
+	GtEq:       ">=",
+	PERCENT:    "%",
+	BitAnd:     "&",
+	BitOr:      "|",
+	BitXor:     "^",
+	ShiftLeft:  "<<",
+	ShiftRight: ">>",
+	TILDE:      "~",
+	ELLIPSIS:   "...",
+	DOT:        ".",
 }
 
 func LookupIdent(ident string) Type {
@@ -0,0 +1,98 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"hek/object"
+	"hek/token"
+)
+
+type TraceFrame = object.TraceFrame
+
+// RuntimeError VM 运行时错误, Trace 从出错的帧开始依次到 main
+type RuntimeError struct {
+	Msg   string
+	Trace []TraceFrame
+}
+
+// maxTrace Error 中最多显示的帧数, 过深的调用栈只显示两端
+const maxTrace = 20
+
+func (r *RuntimeError) Error() string {
+	var out bytes.Buffer
+	out.WriteString(r.Msg)
+	for i, frame := range r.Trace {
+		if len(r.Trace) > maxTrace && i == maxTrace/2 {
+			out.WriteString(fmt.Sprintf("\n\t... %d frames omitted", len(r.Trace)-maxTrace))
+		}
+		if len(r.Trace) > maxTrace && i >= maxTrace/2 && i < len(r.Trace)-maxTrace/2 {
+			continue
+		}
+		out.WriteString("\n\tat " + frame.String())
+	}
+	return out.String()
+}
+
+// Pos 返回出错位置
+func (r *RuntimeError) Pos() token.Position {
+	if len(r.Trace) == 0 {
+		return token.Position{}
+	}
+	return r.Trace[0].Pos
+}
+
+func (v *VM) trace() []TraceFrame {
+	trace := make([]TraceFrame, 0, v.frameIndex)
+	for i := v.frameIndex - 1; i >= 0; i-- {
+		f := v.frame[i]
+		trace = append(trace, TraceFrame{
+			Name: f.fn.Name,
+			Ip:   f.opPos,
+			Pos:  f.fn.Lines.Lookup(f.opPos),
+		})
+	}
+	return trace
+}
+
+// handler try 语句安装的错误处理
+type handler struct {
+	frameIndex int //安装时的帧数
+	sp         int
+	ip         int //catch 代码的地址
+}
+
+// errors 抛出 VM 运行时错误
+func (v *VM) errors(msg string) {
+	v.throw(&object.Error{Msg: msg})
+}
+
+// throw 抛出错误, 一条指令中只保留第一个错误, 指令执行完后由 unwind 处理
+func (v *VM) throw(err *object.Error) {
+	if v.thrown != nil {
+		return
+	}
+	if err.Trace == nil {
+		err.Trace = v.trace()
+	}
+	err.IsValue = false
+	v.thrown = err
+}
+
+// unwind 跳转到最内层的 handler, 并把错误放到栈顶, depth 以下的 handler 属于外层的 run, 此时返回 false
+func (v *VM) unwind(depth int) bool {
+	if len(v.handlers) == 0 || v.handlers[len(v.handlers)-1].frameIndex <= depth {
+		return false
+	}
+	h := v.handlers[len(v.handlers)-1]
+	v.handlers = v.handlers[:len(v.handlers)-1]
+	for v.frameIndex > h.frameIndex {
+		v.popFrame()
+	}
+	err := v.thrown
+	v.thrown = nil
+	err.IsValue = true
+	v.sp = h.sp
+	v.push(err)
+	v.currentFrame().ip = h.ip - 1
+	return true
+}
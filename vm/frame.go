@@ -8,31 +8,40 @@ import (
 type Frame struct {
 	fn    *object.CompliedFun
 	ip    int
-	local []object.Object
+	opPos int //当前执行指令的偏移
+	local []*object.Cell
 }
 
 func NewFrame(fu *object.CompliedFun) *Frame {
 	return &Frame{
-		fn: fu,
-		ip: -1,
+		fn:    fu,
+		ip:    -1,
+		local: make([]*object.Cell, fu.NumLocal),
 	}
 }
 func (f *Frame) Instructions() code.Instructions {
 	return f.fn.Instructions
 }
-func (f *Frame) Push(obj object.Object, index ...int) {
-	if len(index) > 0 && len(f.local)-1 >= len(index) {
-		f.local[index[0]] = obj
-		return
+
+// cell 返回局部变量的 Cell, 被闭包捕获时与闭包共享
+func (f *Frame) cell(index int) *object.Cell {
+	if f.local[index] == nil {
+		f.local[index] = &object.Cell{Value: Null}
 	}
-	f.local = append(f.local, obj)
+	return f.local[index]
+}
+func (f *Frame) Push(obj object.Object, index int) {
+	f.cell(index).Value = obj
 }
 func (f *Frame) Pop(index int) object.Object {
-	return f.local[index]
+	if f.local[index] == nil {
+		return Null
+	}
+	return f.local[index].Value
 }
-func (f *Frame) PushFree(obj object.Object) {
-	f.fn.Free = append(f.fn.Free, obj)
+func (f *Frame) PushFree(obj object.Object, index int) {
+	f.fn.Free[index].Value = obj
 }
 func (f *Frame) PopFree(index int) object.Object {
-	return f.fn.Free[index]
+	return f.fn.Free[index].Value
 }
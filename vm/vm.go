@@ -1,62 +1,96 @@
 package vm
 
 import (
-	"errors"
 	"fmt"
 	"hek/code"
 	"hek/compiler"
 	"hek/object"
+	"math"
 	"strings"
 )
 
-var True = &object.Bool{Value: true}
-var False = &object.Bool{Value: false}
-var Null = &object.Null{}
+var True = object.TRUE
+var False = object.FALSE
+var Null = object.NULL_
 
 const StackSiz = 2048
 const GlobalSiz = 2048
 
+// MaxDepth 默认的最大调用深度, 超过时报 stack overflow
+const MaxDepth = object.MaxDepth
+
 type VM struct {
 	constants []object.Object
 	global    []object.Object
 	stack     []object.Object
 	sp        int
 
-	err    []string
-	errLen int
+	thrown   *object.Error //当前指令抛出的错误
+	handlers []handler     //try 语句安装的 handler, 最内层在最后
 
 	frame      []*Frame
 	frameIndex int
+	maxDepth   int
+
+	ctx *object.Context //内置函数的运行环境, 回调在本 VM 上执行
 }
 
 func NewVM(byteCode *compiler.Bytecode) *VM {
-	main_ := NewFrame(&object.CompliedFun{Instructions: byteCode.Instructions})
-	return &VM{
+	main_ := NewFrame(&object.CompliedFun{Instructions: byteCode.Instructions, Name: "main", Lines: byteCode.Lines})
+	v := &VM{
 		constants:  byteCode.Constants,
 		sp:         0,
 		frame:      []*Frame{main_},
 		frameIndex: 1,
+		maxDepth:   MaxDepth,
 		stack:      make([]object.Object, StackSiz),
 		global:     make([]object.Object, GlobalSiz),
 	}
+	v.ctx = object.NewContext(v.callFun)
+	return v
 }
 func NewVMCache(byteCode *compiler.Bytecode, global []object.Object) *VM {
-	main_ := NewFrame(&object.CompliedFun{Instructions: byteCode.Instructions})
-	return &VM{
+	main_ := NewFrame(&object.CompliedFun{Instructions: byteCode.Instructions, Name: "main", Lines: byteCode.Lines})
+	v := &VM{
 		constants:  byteCode.Constants,
 		frame:      []*Frame{main_},
 		frameIndex: 1,
+		maxDepth:   MaxDepth,
 		global:     global,
 		stack:      make([]object.Object, StackSiz),
 	}
+	v.ctx = object.NewContext(v.callFun)
+	return v
+}
+
+// SetMaxDepth 设置最大调用深度, 包含 main
+func (v *VM) SetMaxDepth(depth int) {
+	v.maxDepth = depth
+}
+
+// Context 返回内置函数的运行环境, 可以修改输出位置与脚本参数
+func (v *VM) Context() *object.Context {
+	return v.ctx
 }
 func (v *VM) LastPoppedStackElem() object.Object {
 	return v.stack[v.sp]
 }
 func (v *VM) Run() error {
-	for v.currentFrame().ip < len(v.currentFrame().Instructions())-1 {
+	v.run(0)
+	if v.thrown != nil {
+		err := v.thrown
+		v.thrown = nil
+		return &RuntimeError{Msg: err.Msg, Trace: err.Trace}
+	}
+	return nil
+}
+
+// run 执行指令, 直到出错或帧数回到 depth
+func (v *VM) run(depth int) {
+	for v.frameIndex > depth && v.currentFrame().ip < len(v.currentFrame().Instructions())-1 {
 		v.currentFrame().ip++
 		frame := v.currentFrame()
+		frame.opPos = frame.ip
 
 		op := code.Opcode(frame.Instructions()[frame.ip])
 
@@ -64,15 +98,16 @@ func (v *VM) Run() error {
 		case code.OpConstant:
 			constOIndex := v.getUint()
 			v.push(v.constants[constOIndex])
-		case code.OpAdd, code.OpSub, code.OpDiv, code.OpMul:
+		case code.OpAdd, code.OpSub, code.OpDiv, code.OpMul, code.OpMod,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr:
 			v.operation(op)
 		case code.OpPop:
 			v.pop()
 		case code.OpTrue, code.OpFalse:
 			v.bool(op)
-		case code.OpGT, code.OpLT, code.OpEqual, code.OpNotEqual:
+		case code.OpGT, code.OpLT, code.OpGTE, code.OpLTE, code.OpEqual, code.OpNotEqual:
 			v.compare(op)
-		case code.OpBang, code.OpMinus, code.OpTwoSub, code.OpTwoAdd:
+		case code.OpBang, code.OpMinus, code.OpBitNot, code.OpTwoSub, code.OpTwoAdd:
 			v.prefix(op)
 		case code.OpJumpNotTrueThy:
 			jumpIndex := v.getUint()
@@ -98,10 +133,32 @@ func (v *VM) Run() error {
 			}
 		case code.OpArray:
 			v.array()
+		case code.OpHash:
+			v.hash()
+		case code.OpStringBuild:
+			v.stringBuild()
 		case code.OpIndex:
 			v.index()
-		case code.OpCall:
-			v.call()
+		case code.OpSlice:
+			v.slice()
+		case code.OpCall, code.OpCallNamed:
+			v.call(op)
+		case code.OpCallSpread:
+			v.callSpread()
+		case code.OpTry:
+			ip := int(v.getUint())
+			v.handlers = append(v.handlers, handler{frameIndex: v.frameIndex, sp: v.sp, ip: ip})
+		case code.OpPopHandler:
+			v.handlers = v.handlers[:len(v.handlers)-1]
+		case code.OpThrow:
+			v.throw(object.ThrowValue(v.pop()))
+		case code.OpDefaultArg:
+			index := int(v.getUint())
+			target := int(v.getUint())
+			//参数已经传入时跳过默认值
+			if frame.local[index] != nil {
+				frame.ip = target - 1
+			}
 		case code.OpReturnValue, code.OpReturn:
 			v.returnValue(op)
 		case code.OpSetLocal:
@@ -117,17 +174,60 @@ func (v *VM) Run() error {
 		case code.OpGetFree:
 			index := int(v.getUint())
 			v.push(frame.PopFree(index))
-		case code.OpSetIndexGlobal, code.OpSetIndexLocal:
+		case code.OpSetFree:
+			index := int(v.getUint())
+			frame.PushFree(v.pop(), index)
+		case code.OpSetIndexGlobal, code.OpSetIndexLocal, code.OpSetIndexFree:
 			v.indexSet(op)
+		case code.OpSetIndex:
+			key := v.pop()
+			val := v.pop()
+			if err := object.SetIndex(val, key, v.pop()); err != nil {
+				v.errors(err.Msg)
+			}
 		default:
 			v.errors("VM Op err")
 		}
 
-		if v.errLen > 0 {
-			return errors.New(v.echoError())
+		if v.thrown != nil && !v.unwind(depth) {
+			return
 		}
 	}
-	return nil
+}
+
+// callFun 供内置函数回调 hek 函数, 在当前 VM 上执行直到函数返回
+// 函数中没有被捕获的错误会返回给内置函数, 内置函数返回后再由 VM 继续抛出
+func (v *VM) callFun(fn object.Object, args ...object.Object) (object.Object, *object.Error) {
+	f, ok := fn.(*object.CompliedFun)
+	if !ok {
+		return nil, &object.Error{Msg: "调用的不是一个方法"}
+	}
+	frame, err := v.newFrame(f, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	base := v.sp
+	depth := v.frameIndex
+	v.pushFrame(frame)
+	v.run(depth)
+	if v.thrown != nil {
+		err := v.thrown
+		v.thrown = nil
+		for v.frameIndex > depth {
+			v.popFrame()
+		}
+		v.sp = base
+		return nil, err
+	}
+	var result object.Object = Null
+	if v.frameIndex > depth {
+		//函数没有返回指令, 执行完最后一条指令后结束
+		v.popFrame()
+	} else {
+		result = v.pop()
+	}
+	v.sp = base
+	return result, nil
 }
 func (v *VM) push(object_ object.Object) {
 	if v.sp >= StackSiz {
@@ -155,11 +255,39 @@ func (v *VM) operation(op code.Opcode) {
 		obj = v.operationString(op, left.(*object.String), right.(*object.String))
 	} else if left.Type() == object.INT && right.Type() == object.INT {
 		obj = v.operationINT(op, left.(*object.Integer), right.(*object.Integer))
+	} else if object.IsNumber(left) && object.IsNumber(right) {
+		l, _ := object.ToFloat(left)
+		r, _ := object.ToFloat(right)
+		obj = v.operationFloat(op, l, r)
 	} else {
 		v.errors(fmt.Sprintf("操作类型不一致 %s - %s", left.Type().String(), right.Type().String()))
+		return
 	}
 	v.push(obj)
 }
+
+// operationFloat int 与 float 混合运算时都提升为 float
+func (v *VM) operationFloat(op code.Opcode, left, right float64) object.Object {
+	switch op {
+	case code.OpAdd:
+		return &object.Float{Value: left + right}
+	case code.OpSub:
+		return &object.Float{Value: left - right}
+	case code.OpMul:
+		return &object.Float{Value: left * right}
+	case code.OpDiv, code.OpMod:
+		if right == 0 {
+			v.errors("除数不能为0")
+			return Null
+		}
+		if op == code.OpDiv {
+			return &object.Float{Value: left / right}
+		}
+		return &object.Float{Value: math.Mod(left, right)}
+	}
+	v.errors("float 类型不支持位运算")
+	return Null
+}
 func (v *VM) operationString(op code.Opcode, left, right *object.String) object.Object {
 	if op != code.OpAdd {
 		v.errors("字符串类型只支持 '+' 的操作方式")
@@ -172,25 +300,39 @@ func (v *VM) operationINT(op code.Opcode, left, right *object.Integer) object.Ob
 	switch op {
 	case code.OpAdd:
 		obj = &object.Integer{Value: left.Value + right.Value}
-	case code.OpDiv:
-		obj = &object.Integer{Value: left.Value / right.Value}
+	case code.OpDiv, code.OpMod:
+		if right.Value == 0 {
+			v.errors("除数不能为0")
+			return Null
+		}
+		if op == code.OpDiv {
+			obj = &object.Integer{Value: left.Value / right.Value}
+		} else {
+			obj = &object.Integer{Value: left.Value % right.Value}
+		}
 	case code.OpMul:
 		obj = &object.Integer{Value: left.Value * right.Value}
 	case code.OpSub:
 		obj = &object.Integer{Value: left.Value - right.Value}
+	case code.OpBitAnd:
+		obj = &object.Integer{Value: left.Value & right.Value}
+	case code.OpBitOr:
+		obj = &object.Integer{Value: left.Value | right.Value}
+	case code.OpBitXor:
+		obj = &object.Integer{Value: left.Value ^ right.Value}
+	case code.OpShl, code.OpShr:
+		if right.Value < 0 {
+			v.errors("移位的位数不能为负数")
+			return Null
+		}
+		if op == code.OpShl {
+			obj = &object.Integer{Value: left.Value << right.Value}
+		} else {
+			obj = &object.Integer{Value: left.Value >> right.Value}
+		}
 	}
 	return obj
 }
-func (v *VM) errors(msg string) {
-	v.err = append(v.err, msg)
-	v.errLen++
-}
-func (v *VM) echoError() string {
-	str := strings.Join(v.err, "\n")
-	v.err = []string{}
-	v.errLen = 0
-	return str
-}
 func (v *VM) bool(op code.Opcode) {
 	if op == code.OpTrue {
 		v.push(True)
@@ -203,25 +345,51 @@ func (v *VM) compare(op code.Opcode) {
 	var left object.Object
 	right = v.pop()
 	left = v.pop()
-	if op == code.OpLT || op == code.OpGT {
+	if op == code.OpLT || op == code.OpGT || op == code.OpLTE || op == code.OpGTE {
 		if !v.compareLGCheck(left, right) {
-			v.errors("< 和 > 运算 必须是数字类型")
+			v.errors("< > <= >= 运算 必须是数字类型")
 			return
 		}
 	}
+	if op == code.OpEqual || op == code.OpNotEqual {
+		v.push(v.compareBool(object.Equal(left, right) == (op == code.OpEqual)))
+		return
+	}
+	if (left.Type() == object.FLOAT || right.Type() == object.FLOAT) && object.IsNumber(left) && object.IsNumber(right) {
+		l, _ := object.ToFloat(left)
+		r, _ := object.ToFloat(right)
+		v.push(v.compareFloat(op, l, r))
+		return
+	}
 	var obj object.Object
 	switch op {
-	case code.OpEqual:
-		obj = v.compareBool(left.Inspect() == right.Inspect())
-	case code.OpNotEqual:
-		obj = v.compareBool(left.Inspect() != right.Inspect())
 	case code.OpGT:
 		obj = v.compareBool(left.(*object.Integer).Value > right.(*object.Integer).Value)
 	case code.OpLT:
 		obj = v.compareBool(left.(*object.Integer).Value < right.(*object.Integer).Value)
+	case code.OpGTE:
+		obj = v.compareBool(left.(*object.Integer).Value >= right.(*object.Integer).Value)
+	case code.OpLTE:
+		obj = v.compareBool(left.(*object.Integer).Value <= right.(*object.Integer).Value)
 	}
 	v.push(obj)
 }
+func (v *VM) compareFloat(op code.Opcode, left, right float64) object.Object {
+	switch op {
+	case code.OpEqual:
+		return v.compareBool(left == right)
+	case code.OpNotEqual:
+		return v.compareBool(left != right)
+	case code.OpGT:
+		return v.compareBool(left > right)
+	case code.OpLT:
+		return v.compareBool(left < right)
+	case code.OpGTE:
+		return v.compareBool(left >= right)
+	default:
+		return v.compareBool(left <= right)
+	}
+}
 func (v *VM) compareBool(is bool) object.Object {
 	if is {
 		return True
@@ -229,45 +397,53 @@ func (v *VM) compareBool(is bool) object.Object {
 	return False
 }
 func (v *VM) compareLGCheck(left object.Object, right object.Object) bool {
-	if left.Type() != object.INT || right.Type() != object.INT {
-		return false
-	}
-	return true
+	return object.IsNumber(left) && object.IsNumber(right)
 }
 func (v *VM) prefix(op code.Opcode) {
 	val := v.pop()
 	var obj object.Object
 
+	if op == code.OpBang {
+		v.push(v.prefixBang(val))
+		return
+	}
+	if f, ok := val.(*object.Float); ok && op != code.OpBitNot {
+		v.push(v.prefixFloat(op, f))
+		return
+	}
+	integer, ok := val.(*object.Integer)
+	if !ok {
+		v.errors(fmt.Sprintf("%s 类型不支持该操作", val.Type().String()))
+		return
+	}
 	switch op {
-	case code.OpBang:
-		obj = v.prefixBang(val)
 	case code.OpMinus:
-		obj = &object.Integer{Value: -val.(*object.Integer).Value}
+		obj = &object.Integer{Value: -integer.Value}
+	case code.OpBitNot:
+		obj = &object.Integer{Value: ^integer.Value}
 	case code.OpTwoSub:
-		obj = &object.Integer{Value: val.(*object.Integer).Value - 1}
+		obj = &object.Integer{Value: integer.Value - 1}
 	case code.OpTwoAdd:
-		obj = &object.Integer{Value: val.(*object.Integer).Value + 1}
+		obj = &object.Integer{Value: integer.Value + 1}
 	}
 
 	v.push(obj)
 }
-func (v *VM) prefixBang(obj object.Object) object.Object {
-	switch obj {
-	case True:
-		return False
-	case False:
-		return True
-	case Null:
-		return True
+func (v *VM) prefixFloat(op code.Opcode, val *object.Float) object.Object {
+	switch op {
+	case code.OpMinus:
+		return &object.Float{Value: -val.Value}
+	case code.OpTwoSub:
+		return &object.Float{Value: val.Value - 1}
 	default:
-		return False
+		return &object.Float{Value: val.Value + 1}
 	}
 }
+func (v *VM) prefixBang(obj object.Object) object.Object {
+	return v.compareBool(!object.Truthy(obj))
+}
 func (v *VM) IF(object_ object.Object) bool {
-	if object_ == True {
-		return true
-	}
-	return false
+	return object.Truthy(object_)
 }
 func (v *VM) getUint() uint16 {
 	frame := v.currentFrame()
@@ -283,33 +459,57 @@ func (v *VM) array() {
 	}
 	v.push(obj)
 }
+
+// stringBuild 将栈顶的 n 个值按 Inspect 拼接为字符串
+func (v *VM) stringBuild() {
+	num := int(v.getUint())
+	var out strings.Builder
+	for _, obj := range v.stack[v.sp-num : v.sp] {
+		out.WriteString(obj.Inspect())
+	}
+	v.sp -= num
+	v.push(&object.String{Value: out.String()})
+}
+func (v *VM) hash() {
+	num := int(v.getUint())
+	obj := object.NewHash()
+	for i := v.sp - num; i < v.sp; i += 2 {
+		if err := obj.Set(v.stack[i], v.stack[i+1]); err != nil {
+			v.errors(err.Msg)
+			return
+		}
+	}
+	v.sp -= num
+	v.push(obj)
+}
 func (v *VM) index() {
 	index := v.pop()
 	value := v.pop()
-	if value.Type() != object.ARRAY {
-		v.errors("不能操作数组一样操作普通变量")
-		return
-	}
-	arr := value.(*object.Array)
-	if index.Type() != object.INT {
-		v.errors("数组的索引只能int类型")
-		return
-	}
-	if len(arr.Value) == 0 {
-		v.push(Null)
-		return
-	}
-	i := index.(*object.Integer).Value
-	if int(i) >= len(arr.Value) || i < 0 {
-		v.push(Null)
+	v.pushResult(object.Index(value, index))
+}
+func (v *VM) slice() {
+	end := v.pop()
+	start := v.pop()
+	value := v.pop()
+	v.pushResult(object.Slice(value, start, end))
+}
+
+// pushResult 将 object 包中函数的结果入栈, 结果为错误时转为运行时错误
+func (v *VM) pushResult(obj object.Object) {
+	if err, ok := obj.(*object.Error); ok && !err.IsValue {
+		v.throw(err)
 		return
 	}
-	v.push(arr.Value[i])
+	v.push(obj)
 }
 func (v *VM) currentFrame() *Frame {
 	return v.frame[v.frameIndex-1]
 }
 func (v *VM) pushFrame(f *Frame) {
+	if v.frameIndex >= v.maxDepth {
+		v.errors("stack overflow")
+		return
+	}
 	v.frame = append(v.frame, f)
 	v.frameIndex++
 }
@@ -317,30 +517,92 @@ func (v *VM) popFrame() *Frame {
 	v.frameIndex--
 	tmp := v.frame[v.frameIndex]
 	v.frame = v.frame[:v.frameIndex]
+	//丢弃该帧中 try 语句安装的 handler
+	for len(v.handlers) > 0 && v.handlers[len(v.handlers)-1].frameIndex > v.frameIndex {
+		v.handlers = v.handlers[:len(v.handlers)-1]
+	}
 	return tmp
 }
-func (v *VM) call() {
+
+// call OpCallNamed 的第二个操作数是命名参数名字的常量, 它们的值在参数的最后
+func (v *VM) call(op code.Opcode) {
 	val := int(v.getUint())
+	var names []string
+	if op == code.OpCallNamed {
+		for _, name := range v.constants[v.getUint()].(*object.Array).Value {
+			names = append(names, name.(*object.String).Value)
+		}
+	}
 	fun := v.pop()
-	if fun.Type() == object.BUILTFun {
-		f := fun.(*object.InternalFun)
-		var prams []object.Object
-		prams = v.stack[v.sp-val : v.sp]
-		v.sp -= val
-		obj := f.Fun_(prams...)
-		if obj != nil {
-			v.push(obj)
-		} else {
-			v.push(Null)
+	//复制参数, 内置函数回调 hek 函数时会复用这部分栈
+	prams := make([]object.Object, val)
+	copy(prams, v.stack[v.sp-val:v.sp])
+	v.sp -= val
+	v.callValue(fun, prams, names)
+}
+
+// callSpread 栈上的参数是若干个数组, 拼接后作为参数列表
+func (v *VM) callSpread() {
+	num := int(v.getUint())
+	fun := v.pop()
+	var prams []object.Object
+	for _, part := range v.stack[v.sp-num : v.sp] {
+		arr, ok := part.(*object.Array)
+		if !ok {
+			v.errors(fmt.Sprintf("只能展开 array, 得到 %s", part.Type().String()))
+			return
 		}
-		return
+		prams = append(prams, arr.Value...)
 	}
-	if fun.Type() != object.CompiledFun {
+	v.sp -= num
+	v.callValue(fun, prams, nil)
+}
+func (v *VM) callValue(fun object.Object, prams []object.Object, names []string) {
+	switch f := fun.(type) {
+	case *object.InternalFun:
+		if len(names) > 0 {
+			v.errors("内置函数不支持命名参数")
+			return
+		}
+		obj := f.Fun_(v.ctx, prams...)
+		if obj == nil {
+			v.errors("内置函数没有返回值")
+			return
+		}
+		v.pushResult(obj)
+	case *object.CompliedFun:
+		frame, err := v.newFrame(f, prams, names)
+		if err != nil {
+			v.errors(err.Msg)
+			return
+		}
+		v.pushFrame(frame)
+	default:
 		v.errors("调用的不是一个方法")
-		return
 	}
-	f := fun.(*object.CompliedFun)
-	v.pushFrame(NewFrame(f))
+}
+
+// newFrame 检查参数并按名字绑定命名参数, 将参数放入新帧的局部变量, 多余的参数放入 ...rest
+// 没有传入的参数不设置局部变量, 由 OpDefaultArg 计算默认值
+func (v *VM) newFrame(f *object.CompliedFun, prams []object.Object, names []string) (*Frame, *object.Error) {
+	prams, err := object.BindArgs(f.Name, f.ParamNames, f.NumDefaults, f.Variadic, prams, names)
+	if err != nil {
+		return nil, err
+	}
+	frame := NewFrame(f)
+	for i := 0; i < len(prams) && i < f.NumParams; i++ {
+		if prams[i] != nil {
+			frame.Push(prams[i], i)
+		}
+	}
+	if f.Variadic {
+		rest := []object.Object{}
+		if len(prams) > f.NumParams {
+			rest = append(rest, prams[f.NumParams:]...)
+		}
+		frame.Push(&object.Array{Value: rest}, f.NumParams)
+	}
+	return frame, nil
 }
 func (v *VM) returnValue(op code.Opcode) {
 	var obj object.Object
@@ -362,10 +624,19 @@ func (v *VM) loadFun() {
 	fun := v.constants[constantsIndex].(*object.CompliedFun)
 
 	if freeNum > 0 {
-		params := make([]object.Object, freeNum)
-		copy(params, v.stack[v.sp-freeNum:v.sp])
-		fun = &object.CompliedFun{Instructions: fun.Instructions, Free: params}
-		v.sp -= freeNum
+		//捕获外层函数变量的 Cell, 而不是变量的值
+		frame := v.currentFrame()
+		free := make([]*object.Cell, freeNum)
+		for i, fv := range fun.FreeVars {
+			if fv.Local {
+				free[i] = frame.cell(fv.Index)
+			} else {
+				free[i] = frame.fn.Free[fv.Index]
+			}
+		}
+		closure := *fun
+		closure.Free = free
+		fun = &closure
 	}
 	v.push(fun)
 }
@@ -374,27 +645,18 @@ func (v *VM) indexSet(op code.Opcode) {
 	//全局
 	//局部
 	index := int(v.getUint())
-	arrIndex := v.pop()
+	key := v.pop()
 	value := v.pop()
-	if op == code.OpSetIndexGlobal {
-		//全局状态设置
-		val := v.global[index]
-		arr := val.(*object.Array)
-
-		if arrIndex.Type() != object.INT {
-			v.errors("数组索引只能是数字类型")
-			return
-		}
-		arr.Value[arrIndex.(*object.Integer).Value] = value
-		v.constants[index] = arr
-	} else {
-		val := v.currentFrame().local[index]
-		arr := val.(*object.Array)
-		if arrIndex.Type() != object.INT {
-			v.errors("数组索引只能是数字类型")
-			return
-		}
-		arr.Value[arrIndex.(*object.Integer).Value] = value
-		v.currentFrame().local[index] = arr
+	var val object.Object
+	switch op {
+	case code.OpSetIndexGlobal:
+		val = v.global[index]
+	case code.OpSetIndexLocal:
+		val = v.currentFrame().Pop(index)
+	default:
+		val = v.currentFrame().PopFree(index)
+	}
+	if err := object.SetIndex(val, key, value); err != nil {
+		v.errors(err.Msg)
 	}
 }
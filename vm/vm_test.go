@@ -1,43 +1,959 @@
 package vm
 
 import (
-	"fmt"
+	"bytes"
+	"hek/ast"
 	"hek/compiler"
 	"hek/lexer"
+	"hek/object"
 	"hek/parser"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
-func TestVM(t *testing.T) {
-	f, _ := os.Open("./test.txt")
-	defer f.Close()
-	fileInfo, _ := f.Stat()
-	buf := make([]byte, fileInfo.Size())
-	_, _ = f.Read(buf)
-	input := string(buf)
+// TestConformance testdata 下的每个 .hek 程序分别由解释器与 VM 执行
+// 输出必须与同名的 .out 文件一致, 出错时错误信息必须与 .err 文件一致, 文件不存在表示没有输出或不应出错
+func TestConformance(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.hek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("testdata 中没有 .hek 程序")
+	}
+	engines := []struct {
+		name string
+		run  func(program *ast.Program) (string, string)
+	}{
+		{"eval", runEval},
+		{"vm", runCompiled},
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file, ".hek")
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			src := readTestdata(t, file)
+			wantOut := readTestdata(t, name+".out")
+			wantErr := strings.TrimSpace(readTestdata(t, name+".err"))
+
+			p := parser.NewParser(lexer.NewLexerFile(filepath.Base(file), src))
+			program := p.ParseProgram()
+			for _, engine := range engines {
+				out, errMsg := "", strings.Join(p.Errors(), "\n")
+				if len(p.Errors()) == 0 {
+					out, errMsg = engine.run(program)
+				}
+				if out != wantOut {
+					t.Errorf("%s 输出:\n%s\n期望:\n%s", engine.name, out, wantOut)
+				}
+				if errMsg != wantErr {
+					t.Errorf("%s 错误: %q, 期望: %q", engine.name, errMsg, wantErr)
+				}
+			}
+		})
+	}
+}
+
+// readTestdata 读取测试文件, 文件不存在时返回空字符串
+func readTestdata(t *testing.T, path string) string {
+	t.Helper()
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
 
-	lexer_ := lexer.NewLexer(input)
+func runEval(program *ast.Program) (string, string) {
+	var out bytes.Buffer
+	env := object.NewEnv(nil)
+	env.Context().Out = &out
+	result := object.Eval(program, env)
+	if err, ok := result.(*object.Error); ok && !err.IsValue {
+		return out.String(), err.Msg
+	}
+	return out.String(), ""
+}
+func runCompiled(program *ast.Program) (string, string) {
+	compile := compiler.NewCompile()
+	if err := compile.Compile(program); err != nil {
+		return "", err.Error()
+	}
+	var out bytes.Buffer
+	vm_ := NewVM(compile.ByteCode())
+	vm_.Context().Out = &out
+	if err := vm_.Run(); err != nil {
+		return out.String(), err.(*RuntimeError).Msg
+	}
+	return out.String(), ""
+}
 
-	p := parser.NewParser(lexer_)
+func TestRuntimeErrorTrace(t *testing.T) {
+	input := "let inner = fun(a) {\n  return a();\n};\nfun outer() {\n  inner(5);\n}\nouter();"
 
+	p := parser.NewParser(lexer.NewLexerFile("t.hek", input))
 	program := p.ParseProgram()
+	compile := compiler.NewCompile()
+	if err := compile.Compile(program); err != nil {
+		t.Fatal(err)
+	}
+	err := NewVM(compile.ByteCode()).Run()
+	rErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("err is %T (%v), want *RuntimeError", err, err)
+	}
+	want := []struct {
+		name string
+		line int
+	}{
+		{"inner", 2},
+		{"outer", 5},
+		{"main", 7},
+	}
+	if len(rErr.Trace) != len(want) {
+		t.Fatalf("trace len %d, want %d\n%s", len(rErr.Trace), len(want), rErr)
+	}
+	for i, w := range want {
+		frame := rErr.Trace[i]
+		if frame.Name != w.name || frame.Pos.Line != w.line || frame.Pos.File != "t.hek" {
+			t.Errorf("trace[%d] = %s, want %s at line %d", i, frame, w.name, w.line)
+		}
+	}
+}
+
+func TestEvalTrace(t *testing.T) {
+	tests := []string{
+		"let inner = fun(a) {\n  return a();\n};\nfun outer() {\n  inner(5);\n}\nouter();",
+		"let f = fun(x) {\n  return 1 / x\n}\nmap([1, 0], f)",
+		"fun g(a) { return a }\nfun h() {\n  g()\n}\nh()",
+		"fun f() {\n  let e = error(\"v\")\n  throw e\n}\nf()",
+		"fun f() { throw \"x\" }\nlet e = 0\ntry { f() } catch (err) { e = err }\nthrow e",
+	}
+	for _, input := range tests {
+		program := parser.NewParser(lexer.NewLexerFile("t.hek", input)).ParseProgram()
+		evalErr, ok := object.Eval(program, object.NewEnv(nil)).(*object.Error)
+		if !ok {
+			t.Fatalf("%q: eval did not fail", input)
+		}
+		compile := compiler.NewCompile()
+		if err := compile.Compile(program); err != nil {
+			t.Fatal(err)
+		}
+		vmErr, ok := NewVM(compile.ByteCode()).Run().(*RuntimeError)
+		if !ok {
+			t.Fatalf("%q: vm did not fail", input)
+		}
+		if len(evalErr.Trace) != len(vmErr.Trace) {
+			t.Fatalf("%q: eval trace %v, vm trace %v", input, evalErr.Trace, vmErr.Trace)
+		}
+		for i, frame := range vmErr.Trace {
+			got := evalErr.Trace[i]
+			if got.Name != frame.Name || got.Pos != frame.Pos {
+				t.Errorf("%q: eval trace[%d] = %s, vm = %s", input, i, got, frame)
+			}
+		}
+	}
+}
 
+func runVM(t *testing.T, input string) object.Object {
+	t.Helper()
+	p := parser.NewParser(lexer.NewLexer(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse %q: %v", input, p.Errors())
+	}
 	compile := compiler.NewCompile()
+	if err := compile.Compile(program); err != nil {
+		t.Fatalf("compile %q: %s", input, err)
+	}
+	vm_ := NewVM(compile.ByteCode())
+	if err := vm_.Run(); err != nil {
+		t.Fatalf("run %q: %s", input, err)
+	}
+	return vm_.LastPoppedStackElem()
+}
 
-	err := compile.Compile(program)
-	if err != nil {
-		t.Error(err)
-		return
+// runVMInspect 返回运行结果的 Inspect, 运行时错误返回错误信息
+func runVMInspect(t *testing.T, input string) string {
+	t.Helper()
+	program := parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+	compile := compiler.NewCompile()
+	if err := compile.Compile(program); err != nil {
+		t.Fatalf("compile %q: %s", input, err)
+	}
+	vm_ := NewVM(compile.ByteCode())
+	if err := vm_.Run(); err != nil {
+		return err.(*RuntimeError).Msg
+	}
+	return vm_.LastPoppedStackElem().Inspect()
+}
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"let k = \"a\"; {k: 1}[k]", "1"},
+		{"{\"a\": 1}[\"a\"]", "1"},
+		{"{1: \"x\", true: \"y\"}[true]", "y"},
+		{"let h = {\"a\": 1}; h[\"a\"] = 2; h[\"b\"] = 3; h", "{a:2,b:3}"},
+		{"fun f() { let h = {}; h[\"k\"] = [1]; return h[\"k\"]; } f()", "[1]"},
+		{"{}[1]", "null"},
+		{"{\"b\": 1, \"a\": 2, 3: 3}", "{b:1,a:2,3:3}"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestHashUnhashableKey(t *testing.T) {
+	tests := []string{
+		"{[1]: 1}",
+		"{}[fun() {}]",
+		"let h = {}; h[{}] = 1",
+	}
+	for _, input := range tests {
+		compile := compiler.NewCompile()
+		if err := compile.Compile(parser.NewParser(lexer.NewLexer(input)).ParseProgram()); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewVM(compile.ByteCode()).Run(); err == nil {
+			t.Errorf("%q: expected unhashable key error", input)
+		}
+	}
+}
+
+func TestLoop(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"let s = 0; for (let i = 0; i < 10; i++) { if (i == 3) { continue; } if (i == 6) { break; } s = s + i; } s", "12"},
+		{"let c = 0; outer: for (let i = 0; i < 5; i++) { for (let j = 0; j < 5; j++) { if (j == 2) { continue outer; } if (i == 3) { break outer; } c = c + 1; } } c", "6"},
+		{"fun f() { let n = 0; for (let i = 10; i > 0; i--) { if (i < 5) { break; } n++; } return n; } f()", "6"},
+		{"let n = 0; for (let i = 0; 10 > i; i++) { n++; } n", "10"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLogical(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"true && true", "true"},
+		{"true && false", "false"},
+		{"false && true", "false"},
+		{"false || true", "true"},
+		{"false || false", "false"},
+		{"1 < 2 && 2 < 3 || false", "true"},
+		{"false && 1 == 1 || true && 2 == 2", "true"},
+		{"let n = 0; fun inc() { n = n + 1; return true; } false && inc(); true || inc(); n", "0"},
+		{"let n = 0; fun inc() { n = n + 1; return true; } true && inc(); false || inc(); n", "2"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"3 <= 3", "true"},
+		{"4 <= 3", "false"},
+		{"3 >= 4", "false"},
+		{"7 % 3", "1"},
+		{"-7 % 3", "-1"},
+		{"6 & 3", "2"},
+		{"6 | 3", "7"},
+		{"6 ^ 3", "5"},
+		{"1 << 4", "16"},
+		{"256 >> 2", "64"},
+		{"~5", "-6"},
+		{"1 + 2 << 1", "6"},
+		{"1 | 6 & 3", "3"},
+		{"2 * 5 % 3", "1"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, input := range []string{"1 / 0", "1 % 0", "fun f(x) { return 10 / x; } f(0)"} {
+		compile := compiler.NewCompile()
+		if err := compile.Compile(parser.NewParser(lexer.NewLexer(input)).ParseProgram()); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewVM(compile.ByteCode()).Run(); err == nil {
+			t.Errorf("%q: expected division by zero error", input)
+		}
+	}
+}
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.5", "1.5"},
+		{".5 + 1", "1.5"},
+		{"1e-3", "0.001"},
+		{"2.5E2", "250.0"},
+		{"1 / 2.0", "0.5"},
+		{"3 * 1.5", "4.5"},
+		{"7.5 % 2", "1.5"},
+		{"-2.5", "-2.5"},
+		{"1.0 == 1", "true"},
+		{"2 > 1.5", "true"},
+		{"1.5 <= 1", "false"},
+		{"let x = 0.5; x++; x", "1.5"},
+		{"(1 + 2 + 3) / 3.0", "2.0"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let name = "hek"; "hello ${name}!"`, "hello hek!"},
+		{`let items = [1, 2]; "${len(items)} items"`, "2 items"},
+		{`"${1 + 2}${3}"`, "33"},
+		{`"a ${ {"k": "${1.5}"}["k"] } b"`, "a 1.5 b"},
+		{`"\${x}"`, "${x}"},
+		{`"${[1, true]}"`, "[1,true]"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestUnicodeString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`len("你好")`, "2"},
+		{`len("a你b")`, "3"},
+		{`str_rev("你好hek")`, "keh好你"},
+		{`let 名字 = "hek"; "我是${名字}"`, "我是hek"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestIndexAndSlice(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"hello"[1]`, "e"},
+		{`"你好"[1]`, "好"},
+		{`"abc"[3]`, "null"},
+		{`[1, 2, 3, 4][1:3]`, "[2,3]"},
+		{`[1, 2, 3, 4][:2]`, "[1,2]"},
+		{`[1, 2, 3, 4][2:]`, "[3,4]"},
+		{`[1, 2, 3, 4][:]`, "[1,2,3,4]"},
+		{`[1, 2, 3, 4][-2:]`, "[3,4]"},
+		{`[1, 2, 3, 4][:-1]`, "[1,2,3]"},
+		{`[1, 2, 3, 4][3:1]`, "[]"},
+		{`[1, 2, 3, 4][-10:10]`, "[1,2,3,4]"},
+		{`"hello"[1:-1]`, "ell"},
+		{`"你好世界"[1:3]`, "好世"},
+		{`let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a`, "[1,2,3]"},
+		{`let s = "hek"; let n = 1; s[n:n + 1]`, "e"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`split("a,b,c", ",")`, "[a,b,c]"},
+		{`join(["a", "b", 1], "-")`, "a-b-1"},
+		{`trim("  hek \n")`, "hek"},
+		{`upper("hek")`, "HEK"},
+		{`lower("HeK")`, "hek"},
+		{`contains("hello", "ell")`, "true"},
+		{`index_of("你好hek", "hek")`, "2"},
+		{`index_of("hek", "x")`, "-1"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`starts_with("hek", "he")`, "true"},
+		{`ends_with("hek", "he")`, "false"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`substr("你好世界", 1, 2)`, "好世"},
+		{`substr("hello", 2)`, "llo"},
+		{`substr("hello", 3, 10)`, "lo"},
+		{`format("%s=%d", "a", 1)`, "a=1"},
+		{`sprintf("%.2f|%v", 1.5, [1])`, "1.50|[1]"},
+		{`format("%5.1f%%|%-3d|%x|%t|%q|%s", 2, 7, 255, true, "a", 1.0)`, "  2.0%|7  |ff|true|\"a\"|1.0"},
+		{`format("%d", "x")`, "format 第 2 个参数必须是 int, 得到 string"},
+		{`format("%f", "x")`, "format 第 2 个参数必须是 int 或 float, 得到 string"},
+		{`format("%t", 1)`, "format 第 2 个参数必须是 bool, 得到 int"},
+		{`format("%s %s", "a")`, "format 的格式需要 2 个参数, 得到 1 个"},
+		{`format("%s", "a", "b")`, "format 的格式需要 1 个参数, 得到 2 个"},
+		{`format("%*d", 1, 2)`, "format 不支持 %*"},
+		{`format("%[1]d", 1)`, "format 不支持 %["},
+		{`format("%y", 1)`, "format 不支持 %y"},
+		{`format("50%")`, "format 的格式不完整"},
+		{`split("a")`, "split 需要 2 个参数, 得到 1 个"},
+		{`upper(1)`, "upper 第 1 个参数必须是 string, 得到 int"},
+		{`repeat("a", -1)`, "repeat 的次数不能为负数"},
+		{`substr("abc", 4)`, "substr 的开始位置 4 超出范围"},
+		{`substr("abcdef", 1, -3)`, "substr 的长度 -3 不能为负数"},
+		{`substr("abcdef", 1, -1)`, "substr 的长度 -1 不能为负数"},
+		{`substr("abcdef", 1, 0)`, ""},
+		{`substr("abc")`, "substr 需要 2 到 3 个参数, 得到 1 个"},
+		{`substr("abc", 1, "x")`, "substr 第 3 个参数必须是 int, 得到 string"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`first([1, 2, 3])`, "1"},
+		{`first([])`, "null"},
+		{`last([1, 2, 3])`, "3"},
+		{`rest([1, 2, 3])`, "[2,3]"},
+		{`rest([])`, "null"},
+		{`let a = [1]; push(a, 2, 3); a`, "[1,2,3]"},
+		{`let a = [1, 2]; pop(a) + len(a)`, "3"},
+		{`let a = [1, 3]; insert(a, 1, 2); a`, "[1,2,3]"},
+		{`let a = [1, 2, 3]; remove(a, 0); a`, "[2,3]"},
+		{`reverse([1, 2, 3])`, "[3,2,1]"},
+		{`concat([1], [], [2, 3])`, "[1,2,3]"},
+		{`contains([1, "a"], "a")`, "true"},
+		{`contains([1, 2], "1")`, "false"},
+		{`index_of([1, 2, 3], 3)`, "2"},
+		{`sort([3, 1.5, 2])`, "[1.5,2,3]"},
+		{`sort(["b", "c", "a"])`, "[a,b,c]"},
+		{`sort_by(["ccc", "a", "bb"], len)`, "[a,bb,ccc]"},
+		{`sort_by([3, 1, 2], fun(x) { return -x })`, "[3,2,1]"},
+		{`map([1, 2, 3], fun(x) { return x * 2 })`, "[2,4,6]"},
+		{`let n = 10; map([1, 2], fun(x) { return x + n })`, "[11,12]"},
+		{`filter(range(6), fun(x) { return x % 2 == 0 })`, "[0,2,4]"},
+		{`reduce([1, 2, 3, 4], fun(a, b) { return a + b })`, "10"},
+		{`reduce([1, 2, 3], fun(a, b) { return a + b }, 10)`, "16"},
+		{`map([[1, 2], [3]], fun(x) { return map(x, fun(y) { return y * y }) })`, "[[1,4],[9]]"},
+		{`range(3)`, "[0,1,2]"},
+		{`range(1, 4)`, "[1,2,3]"},
+		{`range(5, 0, -2)`, "[5,3,1]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1,a],[2,b]]"},
+		{`first(1)`, "first 第 1 个参数必须是 array, 得到 int"},
+		{`remove([1], 1)`, "remove 的下标 1 超出范围"},
+		{`sort([1, "a"])`, "sort 无法比较 string 与 int"},
+		{`map([1], 1)`, "map 第 2 个参数必须是函数, 得到 int"},
+		{`reduce([], fun(a, b) { return a })`, "reduce 空数组时必须提供初始值"},
+		{`range(1, 2, 0)`, "range 的步长不能为0"},
+		{`contains(1, "a")`, "contains 第 1 个参数必须是 string 或 array, 得到 int"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
 	}
-	fmt.Println(compile.String())
+}
+
+func TestCallbackError(t *testing.T) {
+	input := `let f = fun(x) { return x / 0 };
+map([1], f)`
+	program := parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+	compile := compiler.NewCompile()
+	if err := compile.Compile(program); err != nil {
+		t.Fatal(err)
+	}
+	err := NewVM(compile.ByteCode()).Run()
+	rerr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("err = %v, want *RuntimeError", err)
+	}
+	if rerr.Msg != "除数不能为0" {
+		t.Errorf("msg = %q", rerr.Msg)
+	}
+	if len(rerr.Trace) != 2 || rerr.Trace[0].Name != "f" || rerr.Trace[0].Pos.Line != 1 || rerr.Trace[1].Name != "main" {
+		t.Errorf("trace = %v", rerr.Trace)
+	}
+}
 
+func TestTypeBuiltins(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`type(1)`, "int"},
+		{`type(1.5)`, "float"},
+		{`type("a")`, "string"},
+		{`type(true)`, "bool"},
+		{`type([])`, "array"},
+		{`type({})`, "hash"},
+		{`type(first([]))`, "null"},
+		{`type(fun() {})`, "function"},
+		{`type(len)`, "builtin"},
+		{`try { first(1) } catch (e) { type(e) }`, "error"},
+		{`int(3.9)`, "3"},
+		{`int(-3.9)`, "-3"},
+		{`int(true)`, "1"},
+		{`int(" 42 ")`, "42"},
+		{`int("0x10")`, "16"},
+		{`int("1.5")`, `int 无法将 "1.5" 转换为 int`},
+		{`int([])`, "int 不能转换 array 类型"},
+		{`float(2)`, "2.0"},
+		{`float("1e3")`, "1000.0"},
+		{`float("x")`, `float 无法将 "x" 转换为 float`},
+		{`str(12) + str(1.0) + str(true)`, "121.0true"},
+		{`str([1, "a"])`, "[1,a]"},
+		{`bool(0)`, "false"},
+		{`bool("")`, "false"},
+		{`bool([])`, "false"},
+		{`bool({})`, "false"},
+		{`bool(first([]))`, "false"},
+		{`bool("0")`, "true"},
+		{`bool([0])`, "true"},
+		{`is_null(first([]))`, "true"},
+		{`is_null(0)`, "false"},
+		{`type(1, 2)`, "type 需要 1 个参数, 得到 2 个"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let counter = fun() { let n = 0; return fun() { n = n + 1; return n } };
+let c = counter(); c(); c(); c()`, "3"},
+		{`let counter = fun() { let n = 0; return fun() { n++; return n } };
+let a = counter(); let b = counter(); a(); a(); b()`, "1"},
+		{`let make = fun() {
+	let total = 0;
+	let add = fun(x) { total = total + x; return total };
+	let get = fun() { return total };
+	return [add, get]
+};
+let p = make(); p[0](5); p[0](7); p[1]()`, "12"},
+		{`let outer = fun() {
+	let x = 1;
+	return fun() { return fun() { x = x * 10; return x } }
+};
+let f = outer()(); f(); f()`, "100"},
+		{`let a = fun() { let x = 1; let b = fun() { let c = fun() { return x }; x = 2; return c }; return b() }; a()()`, "2"},
+		{`let f = fun() { let arr = [1]; let g = fun() { arr[0] = 9; return arr }; return g() }; f()`, "[9]"},
+		{`let f = fun() { let fns = []; for (let i = 0; i < 3; i++) { push(fns, fun() { return i }) }; return fns }; map(f(), fun(g) { return g() })`, "[3,3,3]"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFunParams(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let sub = fun(a, b) { return a - b }; sub(5, 3)`, "2"},
+		{`let f = fun(a, b = 2) { return a * 10 + b }; f(1)`, "12"},
+		{`let f = fun(a, b = 2) { return a * 10 + b }; f(1, 5)`, "15"},
+		{`let f = fun(a, b = a + 1) { return [a, b] }; f(3)`, "[3,4]"},
+		{`let f = fun(a, ...rest) { return [a, rest] }; f(1, 2, 3)`, "[1,[2,3]]"},
+		{`let f = fun(...rest) { return rest }; f()`, "[]"},
+		{`let f = fun(a, b, c) { return a + b + c }; f(...[1, 2, 3])`, "6"},
+		{`let f = fun(...xs) { return xs }; let arr = [2, 3]; f(1, ...arr, 4, ...[])`, "[1,2,3,4]"},
+		{`let f = fun(a, b = 10, ...rest) { return [a, b, len(rest)] }; f(...[1])`, "[1,10,0]"},
+		{`concat(...[[1], [2]])`, "[1,2]"},
+		{`reduce(["a", "b", "c"], fun(acc, x) { return acc + x })`, "abc"},
+		{`let f = fun() { let g = fun(x, y = x) { return y }; return g(7) }; f()`, "7"},
+		{`let x = 1; let f = fun() { let x = x + 1; return x }; [f(), x]`, "[2,1]"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestArityErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let add = fun(a, b) { return a + b }; add(1)`, "add 需要 2 个参数, 得到 1 个"},
+		{`let add = fun(a, b) { return a + b }; add(1, 2, 3)`, "add 需要 2 个参数, 得到 3 个"},
+		{`fun f(a, b = 1) { return a }; f()`, "f 需要 1 到 2 个参数, 得到 0 个"},
+		{`let f = fun(a, ...rest) { return a }; f()`, "f 至少需要 1 个参数, 得到 0 个"},
+		{`fun(a) { return a }()`, "匿名函数 需要 1 个参数, 得到 0 个"},
+		{`let f = fun(a) { return a }; f(...1)`, "只能展开 array, 得到 int"},
+		{`map([1], fun(a, b) { return a })`, "匿名函数 需要 2 个参数, 得到 1 个"},
+	}
+	for _, tt := range tests {
+		program := parser.NewParser(lexer.NewLexer(tt.input)).ParseProgram()
+		compile := compiler.NewCompile()
+		if err := compile.Compile(program); err != nil {
+			t.Fatalf("compile %q: %s", tt.input, err)
+		}
+		err := NewVM(compile.ByteCode()).Run()
+		rerr, ok := err.(*RuntimeError)
+		if !ok {
+			t.Errorf("%q: err = %v, want *RuntimeError", tt.input, err)
+			continue
+		}
+		if rerr.Msg != tt.want {
+			t.Errorf("%q: msg = %q, want %q", tt.input, rerr.Msg, tt.want)
+		}
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`fun fact(n) { if (n < 2) { return 1 }; return n * fact(n - 1) }; fact(10)`, "3628800"},
+		{`let fib = fun(n) { if (n < 2) { return n }; fib(n - 1) + fib(n - 2) }; fib(15)`, "610"},
+		{`let even = fun(n) { if (n == 0) { return true }; return odd(n - 1) };
+let odd = fun(n) { if (n == 0) { return false }; return even(n - 1) };
+even(10)`, "true"},
+		{`let f = fun() {
+	let count = fun(n) { if (n == 0) { return 0 }; return 1 + count(n - 1) };
+	return count(50)
+};
+f()`, "50"},
+		{`let f = fun(x) {
+	fun isEven(n) { if (n == 0) { return true }; return isOdd(n - 1) }
+	fun isOdd(n) { if (n == 0) { return false }; return isEven(n - 1) }
+	return isOdd(x)
+};
+f(7)`, "true"},
+		{`let f = fun() { let a = 1 }; f()`, "null"},
+		{`let f = fun(x) { x * 2 }; f(4)`, "8"},
+		{`let f = fun() {}; f()`, "null"},
+		{`let f = fun(n) { for (let i = 0; i < n; i++) {} }; [f(3), 1]`, "[null,1]"},
+	}
+	for _, tt := range tests {
+		if got := runVM(t, tt.input).Inspect(); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	tests := []struct {
+		input string
+		depth int
+	}{
+		{`fun loop(n) { return loop(n + 1) }; loop(0)`, MaxDepth},
+		{`let f = fun(n) { return map([n], f) }; f(0)`, MaxDepth},
+		{`fun loop(n) { return 1 + loop(n) }; loop(0)`, 50},
+	}
+	for _, tt := range tests {
+		program := parser.NewParser(lexer.NewLexer(tt.input)).ParseProgram()
+		compile := compiler.NewCompile()
+		if err := compile.Compile(program); err != nil {
+			t.Fatalf("compile %q: %s", tt.input, err)
+		}
+		vm_ := NewVM(compile.ByteCode())
+		vm_.SetMaxDepth(tt.depth)
+		err := vm_.Run()
+		rerr, ok := err.(*RuntimeError)
+		if !ok {
+			t.Fatalf("%q: err = %v, want *RuntimeError", tt.input, err)
+		}
+		if rerr.Msg != "stack overflow" {
+			t.Errorf("%q: msg = %q", tt.input, rerr.Msg)
+		}
+		if len(rerr.Trace) != tt.depth || rerr.Trace[len(rerr.Trace)-1].Name != "main" {
+			t.Errorf("%q: trace has %d frames", tt.input, len(rerr.Trace))
+		}
+		if !strings.Contains(rerr.Error(), "frames omitted") {
+			t.Errorf("%q: long trace is not shortened", tt.input)
+		}
+	}
+}
+
+func TestEvalStackOverflow(t *testing.T) {
+	tests := []struct {
+		input string
+		depth int
+	}{
+		{`fun loop(n) { return loop(n + 1) }; loop(0)`, MaxDepth},
+		{`let f = fun(n) { return map([n], f) }; f(0)`, MaxDepth},
+		{`fun f() { f() }; f()`, MaxDepth},
+		{`fun loop(n) { return 1 + loop(n) }; loop(0)`, 50},
+	}
+	for _, tt := range tests {
+		program := parser.NewParser(lexer.NewLexer(tt.input)).ParseProgram()
+		env := object.NewEnv(nil)
+		env.SetMaxDepth(tt.depth)
+		err, ok := object.Eval(program, env).(*object.Error)
+		if !ok || err.IsValue || err.Msg != "stack overflow" {
+			t.Errorf("%q: result = %v, want stack overflow", tt.input, err)
+		}
+	}
+	//两个引擎在同样的深度报错, 错误可以被捕获
+	input := `let d = 0; fun f() { d++; f() }; let r = 0; try { f() } catch (e) { r = e.message }; [d, r]`
+	program := parser.NewParser(lexer.NewLexer(input)).ParseProgram()
+	env := object.NewEnv(nil)
+	env.SetMaxDepth(10)
+	got := object.Eval(program, env).Inspect()
+	compile := compiler.NewCompile()
+	if err := compile.Compile(program); err != nil {
+		t.Fatal(err)
+	}
 	vm_ := NewVM(compile.ByteCode())
+	vm_.SetMaxDepth(10)
+	if err := vm_.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := vm_.LastPoppedStackElem().Inspect(); got != want || got != "[9,stack overflow]" {
+		t.Errorf("eval = %s, vm = %s, want [9,stack overflow]", got, want)
+	}
+}
 
-	err = vm_.Run()
-	if err != nil {
-		fmt.Println("vm err:", err)
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let r = 0; try { throw "boom" } catch (e) { r = e["message"] }; r`, "boom"},
+		{`let r = 0; try { 1 + "a" } catch (e) { r = e["message"] }; r`, "操作类型不一致 int - string"},
+		{`let r = 0; try { 1[0] } catch (e) { r = e["message"] }; r`, "int 类型不支持索引操作"},
+		{`let r = 0; try { upper(1) } catch (e) { r = e }; type(r)`, "error"},
+		{`let r = 0; try { throw 1 } catch { r = 2 }; r`, "2"},
+		{`let log = []; try { push(log, 1) } finally { push(log, 2) }; log`, "[1,2]"},
+		{`let log = []; try { try { throw "x" } finally { push(log, "f") } } catch (e) { push(log, e["message"]) }; log`, "[f,x]"},
+		{`let log = []; try { try { throw "x" } catch (e) { throw "y" } finally { push(log, "f") } } catch (e) { push(log, e["message"]) }; log`, "[f,y]"},
+		{`let f = fun() { throw "deep" }; let g = fun() { return f() }; let r = 0;
+try { g() } catch (e) { r = [e["message"], len(e["trace"])] }; r`, "[deep,3]"},
+		{`let log = []; let f = fun() { try { return 1 } finally { push(log, "f") } }; [f(), log]`, "[1,[f]]"},
+		{`let log = []; for (let i = 0; i < 3; i++) { try { if (i == 1) { break }; push(log, i) } finally { push(log, "f") } }; log`, "[0,f,f]"},
+		{`let log = []; for (let i = 0; i < 3; i++) { try { if (i == 1) { continue }; push(log, i) } catch (e) {} }; log`, "[0,2]"},
+		{`map([1, 0], fun(x) { try { return 10 / x } catch (e) { return -1 } })`, "[10,-1]"},
+		{`let r = 0; try { map([0], fun(x) { return 1 / x }) } catch (e) { r = e["message"] }; r`, "除数不能为0"},
+		{`fun loop() { return loop() }; let r = 0; try { loop() } catch (e) { r = e["message"] }; [r, 1 + 1]`, "[stack overflow,2]"},
+		{`1 + fun() { try { throw "x" } catch (e) { return 5 } }()`, "6"},
+		{`let f = fun() { try { 1 } catch (e) { 2 } }; f()`, "null"},
+		{`let r = 0; try { try { throw "a" } catch (e) { throw e } } catch (e) { r = e["message"] }; r`, "a"},
+		{`let e = 0; try { throw "x" } catch (err) { e = err }; first([e])["message"]`, "x"},
+		{`throw "uncaught"`, "uncaught"},
+		{`try { throw "x" } finally { 1 }`, "x"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestErrorValues(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let e = error("bad"); [type(e), e.message, is_error(e)]`, "[error,bad,true]"},
+		{`is_error(1)`, "false"},
+		{`error("bad", {"code": 2}).data.code`, "2"},
+		{`error("bad").data`, "null"},
+		{`let inner = error("inner"); let outer = error("outer", inner); outer.cause.message`, "inner"},
+		{`error("bad", 1).cause`, "null"},
+		{`let div = fun(a, b) { if (b == 0) { return error("除数为0") }; return a / b };
+let r = div(1, 0); if (is_error(r)) { r.message } else { r }`, "除数为0"},
+		{`map([error("a"), 1], is_error)`, "[true,false]"},
+		{`first([error("a")]).message`, "a"},
+		{`let r = 0; try { throw error("x", 7) } catch (e) { r = [e.message, e.data] }; r`, "[x,7]"},
+		{`let r = 0; try { throw [1, {"k": 2}] } catch (e) { r = [e.message, e.data[1].k] }; r`, "[[1,{k:2}],2]"},
+		{`let r = 0; try { throw 1 } catch (e) { r = e.data + 1 }; r`, "2"},
+		{`let r = 0; try { str_rev(1) } catch (e) { r = e.message }; r`, "str_rev 第 1 个参数必须是 string, 得到 int"},
+		{`let r = 0; try { put(1, 2) } catch (e) { r = e.message }; r`, "put 第 1 个参数必须是 array, 得到 int"},
+		{`let r = 0; try { len(1) } catch (e) { r = e.message }; r`, "len 第 1 个参数必须是 string 或 array 或 hash, 得到 int"},
+		{`len({"a": 1})`, "1"},
+		{`let h = {"a": 1}; h.b = 2; h.a + h.b`, "3"},
+		{`let r = 0; try { error("x").nope } catch (e) { r = e.message }; r`, "error 没有 nope 字段"},
+		{`error(1)`, "error 第 1 个参数必须是 string, 得到 int"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"1" == 1`, "false"},
+		{`"1" != 1`, "true"},
+		{`1 == 1.0`, "true"},
+		{`[1, [2, "a"]] == [1, [2, "a"]]`, "true"},
+		{`[1, 2] == [1, 2, 3]`, "false"},
+		{`[1] == ["1"]`, "false"},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, "true"},
+		{`{"a": 1} == {"a": 2}`, "false"},
+		{`first([]) == last([])`, "true"},
+		{`first([]) == false`, "false"},
+		{`fun() {} == fun() {}`, "false"},
+		{`let f = fun() {}; f == f`, "true"},
+		{`let a = [1]; push(a, a); a == a`, "true"},
+		{`index_of([[1], [2]], [2])`, "1"},
+		{`contains([{"a": 1}], {"a": 1})`, "true"},
 	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTruthiness(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`if (1) { "t" } else { "f" }`, "t"},
+		{`if (0) { "t" } else { "f" }`, "f"},
+		{`if (0.0) { "t" } else { "f" }`, "f"},
+		{`if ("x") { "t" } else { "f" }`, "t"},
+		{`if ("") { "t" } else { "f" }`, "f"},
+		{`if ([]) { "t" } else { "f" }`, "f"},
+		{`if ([0]) { "t" } else { "f" }`, "t"},
+		{`if ({}) { "t" } else { "f" }`, "f"},
+		{`if (first([])) { "t" } else { "f" }`, "f"},
+		{`if (fun() {}) { "t" } else { "f" }`, "t"},
+		{`[!0, !1, !"", !"a", ![], ![1], !{}, !first([])]`, "[true,false,true,false,true,false,true,true]"},
+		{`[1 && "a", 0 || [], [] || {"a": 1}, "" && 1]`, "[true,false,true,false]"},
+		{`filter([0, 1, "", "a", [], [2]], fun(x) { x })`, "[1,a,[2]]"},
+		{`let n = 0; for (let i = 3; i; i--) { n++ }; n`, "3"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNestedIndexAssign(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`let h = {"a": {}}; h.a.b = 1; h`, "{a:{b:1}}"},
+		{`let a = {"b": {"c": 1}}; a.b.c = 2; a.b.c`, "2"},
+		{`let a = [[1, 2], [3]]; a[0][1] = 9; a`, "[[1,9],[3]]"},
+		{`fun f() { let a = [[0]]; a[0][0] = 5; return a; } f()`, "[[5]]"},
+		{`let a = [[0]]; fun f() { a[0][0] = 7; } f(); a`, "[[7]]"},
+		{`fun get() { [1] } get()[0] = 2; "ok"`, "ok"},
+		{`let a = [[0]]; a[0][3] = 1`, "数组索引越界 3"},
+		{`let h = {"a": 1}; h.a.b = 1`, "只能对数组或hash的元素赋值"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
 
-	//fmt.Println(vm_.LastPoppedStackElem().Inspect())
+func TestContextIsolation(t *testing.T) {
+	programs := []string{
+		`let s = 0; for (let i = 0; i < 200; i++) { s = s + reduce(map([1, 2, 3], fun(x) { x * 2 }), fun(a, b) { a + b }, 0) } echo(s); echo(args())`,
+		`let s = ""; for (let i = 0; i < 200; i++) { s = join(map(["a", "b"], fun(x) { upper(x) }), "") } echo(s); echo(args())`,
+	}
+	want := []string{"2400\n[first]\n", "AB\n[second]\n"}
+	outs := make([]bytes.Buffer, len(programs))
+	errs := make([]error, len(programs))
+	vms := make([]*VM, len(programs))
+	for i, input := range programs {
+		compile := compiler.NewCompile()
+		if err := compile.Compile(parser.NewParser(lexer.NewLexer(input)).ParseProgram()); err != nil {
+			t.Fatal(err)
+		}
+		vms[i] = NewVM(compile.ByteCode())
+		vms[i].Context().Out = &outs[i]
+	}
+	vms[0].Context().SetArgs([]string{"first"})
+	vms[1].Context().SetArgs([]string{"second"})
+	done := make(chan int)
+	for i := range vms {
+		go func(i int) {
+			errs[i] = vms[i].Run()
+			done <- i
+		}(i)
+	}
+	for range vms {
+		<-done
+	}
+	for i := range vms {
+		if errs[i] != nil {
+			t.Errorf("program %d: %s", i, errs[i])
+		}
+		if got := outs[i].String(); got != want[i] {
+			t.Errorf("program %d output = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestNumberHashKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{1: "a"}[1.0]`, "a"},
+		{`{1.0: "a"}[1]`, "a"},
+		{`{0: "zero"}[-0.0]`, "zero"},
+		{`{1.5: "x"}[1.5]`, "x"},
+		{`{1.5: "x"}[1]`, "null"},
+		{`let h = {}; h[1] = "int"; h[1.0] = "float"; h`, "{1:float}"},
+		{`len({2: 1, 2.0: 2})`, "1"},
+	}
+	for _, tt := range tests {
+		if got := runVMInspect(t, tt.input); got != tt.want {
+			t.Errorf("%q = %s, want %s", tt.input, got, tt.want)
+		}
+	}
 }